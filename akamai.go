@@ -1,16 +1,16 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
-	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/akamai-gtm/render"
 	"github.com/comcast/go-edgegrid/edgegrid"
-	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
 )
 
@@ -18,6 +18,11 @@ import (
 var version string
 
 func main() {
+	newApp().RunAndExitOnError()
+}
+
+// newApp returns the CLI application with its flags and commands.
+func newApp() *cli.App {
 	app := cli.NewApp()
 	app.Name = "akamai-gtm"
 	app.Version = version
@@ -43,6 +48,27 @@ func main() {
 			Usage:  "Luna API Client Secret",
 			EnvVar: "AKAMAI_EDGEGRID_CLIENT_SECRET",
 		},
+		cli.StringFlag{
+			Name:   "hooks",
+			Usage:  "A YAML or JSON file of hooks to run before and after each change",
+			EnvVar: "AKAMAI_GTM_HOOKS",
+		},
+		cli.StringFlag{
+			Name:   "state-dir",
+			Usage:  "The directory to keep local state in, such as the versions updates are based on (default: ~/.akamai-gtm)",
+			EnvVar: "AKAMAI_GTM_STATE_DIR",
+		},
+		cli.StringFlag{
+			Name:   "comment",
+			Usage:  "A comment to record in the history with the changes made",
+			EnvVar: "AKAMAI_GTM_COMMENT",
+		},
+	}
+	app.Before = func(c *cli.Context) error {
+		if err := openHistory(c); err != nil {
+			return err
+		}
+		return loadHooks(c)
 	}
 	app.Commands = []cli.Command{
 		{
@@ -53,57 +79,151 @@ func main() {
 		},
 		{
 			Name:        "domain",
-			Usage:       "domain <domain.akadns.net>",
+			Usage:       "domain [--watch [<interval>]] <domain.akadns.net>",
 			Description: "View the details of a Domain",
+			Flags:       []cli.Flag{watchFlag()},
 			Action:      domain,
 		},
 		{
 			Name:        "domain-create",
-			Usage:       "domain-create --type <domainType> <domain.akadns.net>",
-			Description: "Create a Domain",
-			Flags: []cli.Flag{
+			Usage:       "domain-create [--type <domainType>] [--file <DomainFile>] [--contract <contractId>] [--group <groupId>] [<domain.akadns.net>]",
+			Description: "Create a Domain, optionally from a full definition in a YAML or JSON file and in a given contract and group",
+			Flags: append([]cli.Flag{
 				cli.StringFlag{
 					Name:  "type",
-					Usage: "The Domain type",
+					Usage: "The Domain type; overrides the type in --file",
 				},
-			},
+				cli.StringFlag{
+					Name:  "file, json",
+					Usage: "The path to a YAML or JSON Domain definition, or - for stdin",
+				},
+				cli.StringFlag{
+					Name:  "contract",
+					Usage: "The contract to create the Domain in; see contracts",
+				},
+				cli.StringFlag{
+					Name:  "group",
+					Usage: "The group to create the Domain in; see groups",
+				},
+			}, templateFlags...),
 			Action: domainCreate,
 		},
+		{
+			Name:        "contracts",
+			Usage:       "contracts",
+			Description: "List the contracts the API client can create Domains in",
+			Action:      contracts,
+		},
+		{
+			Name:        "groups",
+			Usage:       "groups [--contract <contractId>]",
+			Description: "List the groups the API client can create Domains in",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "contract",
+					Usage: "Only list the groups of this contract",
+				},
+			},
+			Action: groups,
+		},
 		{
 			Name:        "domain-update",
-			Usage:       "domain-update --json <DomainJSONFile>",
+			Usage:       "domain-update [--merge|--force] --file <DomainFile>",
 			Description: "Update a Domain",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "file, json",
+					Usage: "The path to a YAML or JSON file, or - for stdin",
+				},
+			}, append(templateFlags, conflictFlags...)...),
+			Action: domainUpdate,
+		},
+		{
+			Name:        "domain-patch",
+			Usage:       "domain-patch [--set <path=value>] [--merge-patch <PatchFile>] [--json-patch <PatchFile>] <domain.akadns.net>",
+			Description: "Patch individual fields of a Domain",
+			Flags:       patchFlags,
+			Action:      domainPatch,
+		},
+		{
+			Name:        "domain-delete",
+			Usage:       "domain-delete [--cascade] [--confirm <domain.akadns.net>] [--backup <file>] [--wait <duration>] <domain.akadns.net>",
+			Description: "Delete a Domain after showing its contents, asking to type its name and saving a backup",
 			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "cascade",
+					Usage: "Delete the Domain's properties, maps, resources and data centers first, in that order",
+				},
+				cli.StringFlag{
+					Name:  "confirm",
+					Usage: "The Domain name, to confirm the deletion without being asked",
+				},
 				cli.StringFlag{
-					Name:  "json",
-					Usage: "The path to a JSON file",
+					Name:  "backup",
+					Usage: "The file to save the Domain to before deleting it; defaults to <domain>-<time>.json",
+				},
+				cli.DurationFlag{
+					Name:  "wait",
+					Value: 10 * time.Minute,
+					Usage: "How long to wait for the deletion to be confirmed; 0 to not wait",
 				},
 			},
-			Action: domainUpdate,
+			Action: domainDelete,
 		},
 		{
 			Name:        "data-centers",
-			Usage:       "data-centers <domain.akadns.net>",
+			Usage:       "data-centers [--filter <expr>] [--columns <cols>] [--sort-by <col>] [--no-headers] [--csv] <domain.akadns.net>",
 			Description: "List all DataCenters associated with a Domain",
+			Flags:       listFlags,
 			Action:      dataCenters,
 		},
+		{
+			Name:        "data-centers-import",
+			Usage:       "data-centers-import --csv <file> [--dry-run] [--yes] <domain.akadns.net>",
+			Description: "Create or update DataCenters, matched by nickname, from the rows of a CSV file",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "csv",
+					Usage: "The path to a CSV file with a header row, or - for stdin",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Show what each row would do without submitting anything",
+				},
+				cli.BoolFlag{
+					Name:  "yes",
+					Usage: "Import the rows without asking for confirmation",
+				},
+			},
+			Action: dataCentersImport,
+		},
 		{
 			Name:        "data-centers-delete",
-			Usage:       "data-centers-delete --id <dataCenterId> --id <dataCenterId> <domain.akadns.net>",
+			Usage:       "data-centers-delete [--force] --id <dataCenterId> --id <dataCenterId> <domain.akadns.net>",
 			Description: "Deletes specified DataCenters associated with a Domain",
 			Flags: []cli.Flag{
 				cli.IntSliceFlag{
 					Name:  "id",
 					Usage: "--id <dataCenterId> --id <dataCenterId>",
 				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "Delete even if the DataCenter is referenced by Properties or maps",
+				},
 			},
 			Action: dataCentersDelete,
 		},
 		{
 			Name:        "data-centers-delete-all",
-			Usage:       "data-centers-delete-all <domain.akadns.net>",
+			Usage:       "data-centers-delete-all [--force] <domain.akadns.net>",
 			Description: "Deletes ALL DataCenters associated with a Domain",
-			Action:      dataCentersDeleteAll,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "Delete even if DataCenters are referenced by Properties or maps",
+				},
+			},
+			Action: dataCentersDeleteAll,
 		},
 		{
 			Name:        "data-center",
@@ -119,44 +239,73 @@ func main() {
 		},
 		{
 			Name:        "data-center-create",
-			Usage:       "data-center-create --json <DataCenterJSONFile> <domain.akadns.net>",
-			Description: "Create a DataCenter associated with a Domain from data in a JSON file",
-			Flags: []cli.Flag{
+			Usage:       "data-center-create --file <DataCenterFile> <domain.akadns.net>",
+			Description: "Create a DataCenter associated with a Domain from data in a YAML or JSON file",
+			Flags: append([]cli.Flag{
 				cli.StringFlag{
-					Name:  "json",
-					Usage: "The path to a JSON file",
+					Name:  "file, json",
+					Usage: "The path to a YAML or JSON file, or - for stdin",
 				},
-			},
+			}, templateFlags...),
 			Action: dataCenterCreate,
 		},
 		{
 			Name:        "data-center-update",
-			Usage:       "data-center-update --json <DataCenterJSONFile> <domain.akadns.net>",
-			Description: "Update a DataCenter associated with a Domain from data in a JSON file",
-			Flags: []cli.Flag{
+			Usage:       "data-center-update --file <DataCenterFile> <domain.akadns.net>",
+			Description: "Update a DataCenter associated with a Domain from data in a YAML or JSON file",
+			Flags: append([]cli.Flag{
 				cli.StringFlag{
-					Name:  "json",
-					Usage: "The path to a JSON file",
+					Name:  "file, json",
+					Usage: "The path to a YAML or JSON file, or - for stdin",
 				},
-			},
+			}, templateFlags...),
 			Action: dataCenterUpdate,
 		},
+		{
+			Name:        "data-center-patch",
+			Usage:       "data-center-patch --id <dataCenterId> [--set <path=value>] [--merge-patch <PatchFile>] [--json-patch <PatchFile>] <domain.akadns.net>",
+			Description: "Patch individual fields of a DataCenter associated with a Domain",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "id",
+					Usage: "The data center ID",
+				},
+			}, patchFlags...),
+			Action: dataCenterPatch,
+		},
 		{
 			Name:        "data-center-delete",
-			Usage:       "data-center-delete --id <dataCenterId> <domain.akadns.net>",
+			Usage:       "data-center-delete [--force] --id <dataCenterId> <domain.akadns.net>",
 			Description: "Delete a data center",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "id",
 					Usage: "The data center ID",
 				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "Delete even if the DataCenter is referenced by Properties or maps",
+				},
 			},
 			Action: dataCenterDelete,
 		},
+		{
+			Name:        "data-center-usage",
+			Usage:       "data-center-usage --id <dataCenterId> <domain.akadns.net>",
+			Description: "View the Properties and maps that reference a data center",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "id",
+					Usage: "The data center ID",
+				},
+			},
+			Action: dataCenterUsage,
+		},
 		{
 			Name:        "properties",
-			Usage:       "properties",
+			Usage:       "properties [--filter <expr>] [--columns <cols>] [--sort-by <col>] [--no-headers] [--csv] [--watch [<interval>]] <domain.akadns.net>",
 			Description: "View all Properties of a Domain",
+			Flags:       append([]cli.Flag{watchFlag()}, listFlags...),
 			Action:      properties,
 		},
 		{
@@ -191,28 +340,40 @@ func main() {
 		},
 		{
 			Name:        "property-create",
-			Usage:       "property-create --json <PropertyJSONFile> <domain.akadns.net>",
+			Usage:       "property-create --file <PropertyFile> <domain.akadns.net>",
 			Description: "Create a Property",
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.StringFlag{
-					Name:  "json",
-					Usage: "The path to a JSON file",
+					Name:  "file, json",
+					Usage: "The path to a YAML or JSON file, or - for stdin",
 				},
-			},
+			}, templateFlags...),
 			Action: propertyCreate,
 		},
 		{
 			Name:        "property-update",
-			Usage:       "property-update --json <PropertyJSONFile> <domain.akadns.net>",
+			Usage:       "property-update [--merge|--force] --file <PropertyFile> <domain.akadns.net>",
 			Description: "Update a Property",
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.StringFlag{
-					Name:  "json",
-					Usage: "The path to a JSON file",
+					Name:  "file, json",
+					Usage: "The path to a YAML or JSON file, or - for stdin",
 				},
-			},
+			}, append(templateFlags, conflictFlags...)...),
 			Action: propertyUpdate,
 		},
+		{
+			Name:        "property-patch",
+			Usage:       "property-patch --name <PropertyName> [--set <path=value>] [--merge-patch <PatchFile>] [--json-patch <PatchFile>] <domain.akadns.net>",
+			Description: "Patch individual fields of a Property",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "The Property name",
+				},
+			}, patchFlags...),
+			Action: propertyPatch,
+		},
 		{
 			Name:        "property-delete",
 			Usage:       "property-delete --name <PropertyName> <domain.akadns.net>",
@@ -227,13 +388,14 @@ func main() {
 		},
 		{
 			Name:        "traffic-targets",
-			Usage:       "traffic-targets --name <PropertyName> <domain.akadns.net>",
+			Usage:       "traffic-targets --name <PropertyName> [--watch [<interval>]] <domain.akadns.net>",
 			Description: "View traffic targets associated with a property",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "name",
 					Usage: "The Property name",
 				},
+				watchFlag(),
 			},
 			Action: trafficTargets,
 		},
@@ -249,19 +411,258 @@ func main() {
 			},
 			Action: livenessTests,
 		},
+		{
+			Name:        "liveness-probe",
+			Usage:       "liveness-probe --name <PropertyName> [--test <LivenessTestName>] [--include-disabled] <domain.akadns.net>",
+			Description: "Run a property's liveness tests from the local machine against its traffic target servers",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "The Property name",
+				},
+				cli.StringFlag{
+					Name:  "test",
+					Usage: "Only run the liveness test with this name",
+				},
+				cli.BoolFlag{
+					Name:  "include-disabled",
+					Usage: "Also probe the servers of disabled traffic targets",
+				},
+			},
+			Action: livenessProbe,
+		},
 		{
 			Name:        "status",
-			Usage:       "status <domain.akadns.net>",
+			Usage:       "status [--watch [<interval>]] <domain.akadns.net>",
 			Description: "View the Status details for a Domain",
+			Flags:       []cli.Flag{watchFlag()},
 			Action:      status,
 		},
+		{
+			Name:        "edit",
+			Usage:       "edit property|data-center|domain [--format yaml|json] [--yes] [--name <PropertyName>] [--id <dataCenterId>] <domain.akadns.net>",
+			Description: "Edit a live Property, DataCenter or Domain in $EDITOR and submit it after reviewing the diff",
+			Subcommands: []cli.Command{
+				{
+					Name:        "property",
+					Usage:       "edit property [--format yaml|json] [--yes] --name <PropertyName> <domain.akadns.net>",
+					Description: "Edit a Property",
+					Flags: append([]cli.Flag{
+						cli.StringFlag{
+							Name:  "name",
+							Usage: "The Property name",
+						},
+					}, editFlags...),
+					Action: editProperty,
+				},
+				{
+					Name:        "data-center",
+					Usage:       "edit data-center [--format yaml|json] [--yes] --id <dataCenterId> <domain.akadns.net>",
+					Description: "Edit a DataCenter",
+					Flags: append([]cli.Flag{
+						cli.StringFlag{
+							Name:  "id",
+							Usage: "The data center ID",
+						},
+					}, editFlags...),
+					Action: editDataCenter,
+				},
+				{
+					Name:        "domain",
+					Usage:       "edit domain [--format yaml|json] [--yes] <domain.akadns.net>",
+					Description: "Edit a Domain, including its DataCenters and Properties",
+					Flags:       editFlags,
+					Action:      editDomain,
+				},
+			},
+		},
+		{
+			Name:        "history",
+			Usage:       "history [--object <type:name>] <domain.akadns.net>",
+			Description: "List the changes made to a Domain's objects from here, with who made them and why",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "object",
+					Usage: "Only list the versions of this object, e.g. property:www or datacenter:3131",
+				},
+			},
+			Action: historyList,
+		},
+		{
+			Name:        "history-diff",
+			Usage:       "history-diff <domain.akadns.net> <version> [<version>]",
+			Description: "Compare two versions from history, or a version with the one before it; <version>^ is the state before <version>",
+			Action:      historyDiff,
+		},
+		{
+			Name:        "rollback",
+			Usage:       "rollback --to <version> [--yes] <domain.akadns.net>",
+			Description: "Restore an object to a version from history; <version>^ is the state before <version>",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "The version to restore",
+				},
+				cli.BoolFlag{
+					Name:  "yes",
+					Usage: "Roll back without asking for confirmation",
+				},
+			},
+			Action: rollback,
+		},
+		{
+			Name:        "export",
+			Usage:       "export [--dir <directory>] [--format json|yaml|terraform] <domain.akadns.net>",
+			Description: "Export a Domain, its DataCenters and Properties to a directory with one file per object",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "dir",
+					Usage: "The directory to export to; defaults to the Domain name",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Value: "json",
+					Usage: "The file format: json or yaml, or terraform for Terraform configuration and an import script",
+				},
+			},
+			Action: export,
+		},
+		{
+			Name:        "report",
+			Usage:       "report [--format markdown|html] [--output <file>] (--dir <ExportDirectory> | <domain.akadns.net>)",
+			Description: "Write a readable report of a live Domain or an export directory, with secrets masked",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "dir",
+					Usage: "The export directory to report on instead of a live Domain",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Value: "markdown",
+					Usage: "The report format: markdown, or html for a self-contained page",
+				},
+				cli.StringFlag{
+					Name:  "output, o",
+					Usage: "The file to write the report to; defaults to standard output",
+				},
+			},
+			Action: report,
+		},
+		{
+			Name:        "lint",
+			Usage:       "lint [--config <LintConfigFile>] [--format text|json|sarif] [--fail-on <severity>] (--dir <ExportDirectory> | <domain.akadns.net>)",
+			Description: "Check a live Domain or an export directory against configurable rules",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "dir",
+					Usage: "The export directory to lint instead of a live Domain",
+				},
+				cli.StringFlag{
+					Name:  "config",
+					Usage: "The path to a YAML or JSON lint configuration file",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Value: "text",
+					Usage: "The output format: text, json or sarif",
+				},
+				cli.StringFlag{
+					Name:  "fail-on",
+					Value: "error",
+					Usage: "Exit non-zero if there are findings of this severity or higher: info, warning, error or none",
+				},
+				cli.BoolFlag{
+					Name:  "list-rules",
+					Usage: "List the built-in rules and exit",
+				},
+			},
+			Action: lint,
+		},
+		{
+			Name:        "simulate",
+			Usage:       "simulate (--name <PropertyName> | --file <PropertyFile>) [--down <dataCenterId>] [--down-server <server>] [--client <ip>] [--closest <dataCenterId>] [--map-dc <dataCenterId>] [--load <dataCenterId>=<percent>] [<domain.akadns.net>]",
+			Description: "Compute the answers GTM would hand out for a Property given an assumed data center and server health",
+			Flags:       append(simulateFlags, templateFlags...),
+			Action:      simulate,
+		},
+		{
+			Name:        "weights",
+			Usage:       "weights (--name <PropertyName> | --file <PropertyFile>) [--samples <n>] [--seed <n>] [<domain.akadns.net>]",
+			Description: "View the traffic split of a weighted Property per data center and server, and how it changes when a data center is down",
+			Flags:       append(weightsFlags, templateFlags...),
+			Action:      weights,
+		},
+		{
+			Name:        "search",
+			Usage:       "search [--domain <domain.akadns.net>] [--exact|--regexp] <term>",
+			Description: "Search the Properties of all Domains for servers, CNAMEs, liveness test objects and DataCenters",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "domain",
+					Usage: "Only search this Domain; may be repeated",
+				},
+				cli.BoolFlag{
+					Name:  "exact",
+					Usage: "Match whole values only",
+				},
+				cli.BoolFlag{
+					Name:  "regexp",
+					Usage: "Treat the term as a regular expression",
+				},
+			},
+			Action: search,
+		},
+		{
+			Name:        "render",
+			Usage:       "render --file <TemplateFile> [--vars <VarsFile>] [--set <key=value>]",
+			Description: "Render a JSON template file with the given variables and print the result",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "file, json",
+					Usage: "The path to a YAML or JSON template file, or - for stdin",
+				},
+			}, templateFlags...),
+			Action: renderTemplate,
+		},
+		{
+			Name:        "mock-server",
+			Usage:       "mock-server [--listen <addr>] [--dir <ExportDir>] [--persist] [--domain <name>[:<type>]] [--delay <duration>] [--fail <spec>] [--check-signature]",
+			Description: "Run a local emulator of the GTM API to point --host at",
+			Flags:       mockServerFlags,
+			Action:      mockServer,
+		},
+		{
+			Name:        "exporter",
+			Usage:       "exporter [--listen <addr>] [--path <path>] [--interval <duration>] [--domain <domain.akadns.net>]",
+			Description: "Poll Domains and serve their state and API metrics to Prometheus",
+			Flags:       exporterFlags,
+			Action:      exporter,
+		},
+		{
+			Name:        "tui",
+			Usage:       "tui [<domain.akadns.net>]",
+			Description: "Browse Domains, Properties and DataCenters full-screen, toggle traffic targets and edit Properties",
+			Action:      tui,
+		},
+		{
+			Name:        "completion",
+			Usage:       "completion bash|zsh|fish",
+			Description: "Print a shell completion script, e.g. source <(akamai-gtm completion bash)",
+			Action:      completion,
+		},
+		{
+			Name:            "__complete",
+			Hidden:          true,
+			SkipFlagParsing: true,
+			Action:          complete,
+		},
 	}
-	app.RunAndExitOnError()
+
+	return app
 }
 
 func domains(c *cli.Context) error {
-	client := client(c)
-	domains, err := client.Domains()
+	domains, err := client(c).Domains()
 	if err != nil {
 		return err
 	}
@@ -273,55 +674,86 @@ func domains(c *cli.Context) error {
 }
 
 func domain(c *cli.Context) error {
-	client := client(c)
-	domain, err := client.Domain(c.Args().First())
-	if err != nil {
-		return err
-	}
-	dcs := []string{}
-	for _, dc := range domain.Datacenters {
-		dcs = append(dcs, dc.Nickname)
-	}
-	data := [][]string{
-		[]string{"Name", domain.Name},
-		[]string{"Type", domain.Type},
-		[]string{"DataCenters", strings.Join(dcs, ", ")},
-		[]string{"Status", domain.Status.Message},
-		[]string{"Propagation Status", domain.Status.PropagationStatus},
-		[]string{"Last Modified By", domain.LastModifiedBy},
-		[]string{"Last Modified", domain.LastModified},
-		[]string{"Modification Comments", domain.ModificationComments},
-	}
+	return watched(c, func(w io.Writer, args cli.Args, fields watchFields) error {
+		domain, err := client(c).Domain(args.First())
+		if err != nil {
+			return err
+		}
 
-	printBasicTable(data)
+		rows := render.DomainRows(domain)
+		fields.addRows("", rows)
+		fields["ChangeID"] = domain.Status.ChangeID
+		render.BasicTable(w, rows)
 
-	return nil
+		return nil
+	})
 }
 
 func domainCreate(c *cli.Context) error {
-	client := client(c)
-	domainResp, err := client.DomainCreate(c.Args().First(), c.String("type"))
-	if err != nil {
+	name := c.Args().First()
+	if c.String("file") == "" && c.String("contract") == "" && c.String("group") == "" {
+		domainResp, err := client(c).DomainCreate(name, c.String("type"))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", domainResp.Domain.Name)
+		return nil
+	}
+
+	definition := map[string]interface{}{}
+	if c.String("file") != "" {
+		data, err := readInput(c)
+		if err != nil {
+			return err
+		}
+		doc, err := decodeDocument(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", inputName(c), err)
+		}
+		var ok bool
+		if definition, ok = doc.(map[string]interface{}); !ok {
+			return fmt.Errorf("%s: expected a Domain object", inputName(c))
+		}
+	}
+	if fileName, ok := definition["name"].(string); ok && name != "" && fileName != name {
+		return fmt.Errorf("the Domain is named %s in %s", fileName, inputName(c))
+	} else if name != "" {
+		definition["name"] = name
+	}
+	if typ := c.String("type"); typ != "" {
+		definition["type"] = typ
+	}
+	name, _ = definition["name"].(string)
+	if name == "" {
+		return fmt.Errorf("a Domain name is required")
+	}
+	if typ, _ := definition["type"].(string); typ == "" {
+		return fmt.Errorf("a Domain type is required: use --type or set type")
+	}
+
+	if _, err := client(c).DomainCreateFrom(definition, c.String("contract"), c.String("group")); err != nil {
 		return err
 	}
 
-	fmt.Printf("Created %s\n", domainResp.Domain.Name)
+	fmt.Printf("Created %s\n", name)
 
 	return nil
 }
 
 func domainUpdate(c *cli.Context) error {
-	client := client(c)
 	domainSt := &edgegrid.Domain{}
-	data, err := ioutil.ReadFile(c.String("json"))
-	if err != nil {
+	if err := unmarshalInput(c, domainSt); err != nil {
 		return err
 	}
-	if err := json.Unmarshal(data, domainSt); err != nil {
+
+	api := client(c)
+	submit, err := fileUpdate(c, domainSt.Name, "domain", domainSt.Name, domainSt, func() (interface{}, error) {
+		return api.Domain(domainSt.Name)
+	}, func() interface{} { return &edgegrid.Domain{} })
+	if err != nil {
 		return err
 	}
-
-	domainResp, err := client.DomainUpdate(domainSt)
+	domainResp, err := api.DomainUpdate(submit.(*edgegrid.Domain))
 	if err != nil {
 		return err
 	}
@@ -333,19 +765,20 @@ func domainUpdate(c *cli.Context) error {
 
 func dataCenters(c *cli.Context) error {
 	domain := c.Args().First()
-	client := client(c)
-	dcs, err := client.DataCenters(domain)
+	dcs, err := client(c).DataCenters(domain)
 	if err != nil {
 		return err
 	}
-	data := [][]string{}
+	items := []interface{}{}
 	for _, dc := range dcs {
-		data = append(data, []string{dc.Nickname, strconv.Itoa(dc.DataCenterID)})
+		items = append(items, dc)
 	}
 
-	if len(data) != 0 {
-		printTableWithHeaders([]string{"Nickname", "DataCenter ID"}, data)
-	} else {
+	n, err := printList(os.Stdout, c, items, dataCenterColumns(), []string{"Nickname", "DataCenterID"})
+	if err != nil {
+		return err
+	}
+	if n == 0 && !c.Bool("no-headers") && !c.Bool("csv") {
 		fmt.Printf("No data centers found for domain: %s\n", domain)
 	}
 
@@ -357,27 +790,17 @@ func dataCenter(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	data := [][]string{
-		[]string{"Nickname", dc.Nickname},
-		[]string{"DataCenterID", strconv.Itoa(dc.DataCenterID)},
-		[]string{"City", dc.City},
-		[]string{"CloneOf", strconv.Itoa(dc.CloneOf)},
-		[]string{"Continent", dc.Continent},
-		[]string{"Country", dc.Country},
-		[]string{"Latitude", floatToStr(dc.Latitude)},
-		[]string{"Longitude", floatToStr(dc.Longitude)},
-		[]string{"StateOrProvince", dc.StateOrProvince},
-		[]string{"Virtual", strconv.FormatBool(dc.Virtual)},
-		[]string{"CloudServerTargeting", strconv.FormatBool(dc.CloudServerTargeting)},
-	}
 
-	printBasicTable(data)
+	render.DataCenter(os.Stdout, dc)
 
 	return nil
 }
 
 func dataCenterCreate(c *cli.Context) error {
-	data := unmarshalDc(c)
+	data, err := unmarshalDc(c)
+	if err != nil {
+		return err
+	}
 	dc, err := client(c).DataCenterCreate(c.Args().First(), data)
 	if err != nil {
 		return err
@@ -389,7 +812,10 @@ func dataCenterCreate(c *cli.Context) error {
 }
 
 func dataCenterUpdate(c *cli.Context) error {
-	data := unmarshalDc(c)
+	data, err := unmarshalDc(c)
+	if err != nil {
+		return err
+	}
 	dc, err := client(c).DataCenterUpdate(c.Args().First(), data)
 	if err != nil {
 		return err
@@ -400,24 +826,21 @@ func dataCenterUpdate(c *cli.Context) error {
 	return nil
 }
 
-func unmarshalDc(c *cli.Context) *edgegrid.DataCenter {
+func unmarshalDc(c *cli.Context) (*edgegrid.DataCenter, error) {
 	dcSt := &edgegrid.DataCenter{}
-	data, err := ioutil.ReadFile(c.String("json"))
-	if err != nil {
-		panic(err)
-	}
-	if err := json.Unmarshal(data, dcSt); err != nil {
-		panic(err)
+	if err := unmarshalInput(c, dcSt); err != nil {
+		return nil, err
 	}
 
-	return dcSt
+	return dcSt, nil
 }
 
 func dataCenterDelete(c *cli.Context) error {
 	id := c.Int("id")
-	err := client(c).DataCenterDelete(c.Args().First(), id)
+	dcs := []edgegrid.DataCenter{{DataCenterID: id}}
+	err := gtm.DeleteDataCenters(client(c), c.Args().First(), dcs, !c.Bool("force"), nil)
 	if err != nil {
-		return err
+		return deleteError(err)
 	}
 
 	fmt.Printf("Deleted data center %d\n", id)
@@ -426,108 +849,82 @@ func dataCenterDelete(c *cli.Context) error {
 }
 
 func dataCentersDelete(c *cli.Context) error {
-	ids := c.IntSlice("id")
-	client := client(c)
-	domainName := c.Args().First()
+	dcs := []edgegrid.DataCenter{}
+	for _, id := range c.IntSlice("id") {
+		dcs = append(dcs, edgegrid.DataCenter{DataCenterID: id})
+	}
 
-	for _, id := range ids {
-		err := client.DataCenterDelete(domainName, id)
-		if err != nil {
-			fmt.Printf("Failed to delete DataCenter: %d\n", id)
-			fmt.Printf("Error is: %v", err)
+	err := gtm.DeleteDataCenters(client(c), c.Args().First(), dcs, !c.Bool("force"), deleteProgress("DataCenter"))
 
-			return err
-		}
+	return deleteError(err)
+}
 
-		fmt.Printf("Deleted DataCenter: %d\n", id)
-	}
+func dataCentersDeleteAll(c *cli.Context) error {
+	err := gtm.DeleteAllDataCenters(client(c), c.Args().First(), !c.Bool("force"), deleteProgress("DC"))
 
-	return nil
+	return deleteError(err)
 }
 
-func dataCentersDeleteAll(c *cli.Context) error {
-	myClient := client(c)
-	domainName := c.Args().First()
-	dcs, err := myClient.DataCenters(domainName)
-	if err != nil {
-		return err
-	}
-	for _, dc := range dcs {
-		err := myClient.DataCenterDelete(domainName, dc.DataCenterID)
+// deleteProgress prints the outcome of each delete of a bulk operation.
+func deleteProgress(kind string) gtm.Progress {
+	return func(name string, err error) {
 		if err != nil {
-			fmt.Printf("Failed to delete DataCenter: %s\n", dc.Nickname)
+			fmt.Printf("Failed to delete %s: %s\n", kind, name)
 			fmt.Printf("Error is: %v", err)
-
-			return err
+			return
 		}
-
-		fmt.Printf("Deleted DC: %s (%d)\n", dc.Nickname, dc.DataCenterID)
+		fmt.Printf("Deleted %s: %s\n", kind, name)
 	}
-
-	return nil
-}
-
-// Properties is a Property slice
-type Properties []Property
-
-// Property is an Akamai GTM property
-type Property struct {
-	GtmProperty edgegrid.Property
-	Product     string
-}
-
-func (props Properties) Len() int {
-	return len(props)
 }
 
-func (props Properties) Less(i, j int) bool {
-	return props[i].Product < props[j].Product
-}
-
-func (props Properties) Swap(i, j int) {
-	props[i], props[j] = props[j], props[i]
-}
-
-func buildProps(props *edgegrid.Properties) Properties {
-	created := Properties{}
+// deleteError prints the references to data centers that could not be
+// deleted because they are still in use.
+func deleteError(err error) error {
+	inUse, ok := err.(*gtm.InUseError)
+	if !ok {
+		return err
+	}
 
-	for _, prop := range props.Properties {
-		splitName := strings.Split(prop.Name, ".")
-		created = append(created, Property{
-			GtmProperty: prop,
-			Product:     splitName[len(splitName)-1],
-		})
+	for _, dc := range inUse.DataCenters {
+		name := strconv.Itoa(dc.DataCenterID)
+		if dc.Nickname != "" {
+			name = fmt.Sprintf("%s (%d)", dc.Nickname, dc.DataCenterID)
+		}
+		fmt.Printf("DataCenter %s is referenced by:\n", name)
+		render.References(os.Stdout, inUse.References[dc.DataCenterID])
 	}
 
-	return created
+	return fmt.Errorf("refusing to delete %v; use --force to delete anyway", err)
 }
 
 func properties(c *cli.Context) error {
-	domain := c.Args().First()
-	ps, err := client(c).Properties(domain)
-	if err != nil {
-		return err
-	}
-
-	props := buildProps(ps)
-	sort.Sort(props)
+	return watched(c, func(w io.Writer, args cli.Args, fields watchFields) error {
+		domain := args.First()
+		ps, err := client(c).Properties(domain)
+		if err != nil {
+			return err
+		}
 
-	s := [][]string{}
-	for _, prop := range props {
-		s = append(s, []string{
-			prop.GtmProperty.Name,
-			prop.GtmProperty.Type,
-			strings.Join(targetIds(prop.GtmProperty.TrafficTargets), ", "),
-		})
-	}
+		items := []interface{}{}
+		for _, prop := range gtm.SortedProperties(ps) {
+			items = append(items, prop)
+			fields[prop.GtmProperty.Name+"/Type"] = prop.GtmProperty.Type
+			fields[prop.GtmProperty.Name+"/LastModified"] = prop.GtmProperty.LastModified
+			for _, target := range prop.GtmProperty.TrafficTargets {
+				fields.addRows(fmt.Sprintf("%s/%d/", prop.GtmProperty.Name, target.DataCenterID), render.TrafficTargetRows(target))
+			}
+		}
 
-	if len(s) != 0 {
-		printTableWithHeaders([]string{"Name", "Type", "Traffic Targets"}, s)
-	} else {
-		fmt.Printf("No properties found for domain: %s\n", domain)
-	}
+		n, err := printList(w, c, items, propertyColumns(), []string{"Name", "Type", "TrafficTargets"})
+		if err != nil {
+			return err
+		}
+		if n == 0 && !c.Bool("no-headers") && !c.Bool("csv") {
+			fmt.Fprintf(w, "No properties found for domain: %s\n", domain)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func property(c *cli.Context) error {
@@ -536,46 +933,56 @@ func property(c *cli.Context) error {
 		return err
 	}
 
-	printProp(prop)
+	render.Property(os.Stdout, prop)
 
 	return nil
 }
 
 func propertyCreate(c *cli.Context) error {
-	data := unmarshalProp(c)
+	data, err := unmarshalProp(c)
+	if err != nil {
+		return err
+	}
 	prop, err := client(c).PropertyCreate(c.Args().First(), data)
 	if err != nil {
 		return err
 	}
 
-	printProp(prop.Property)
+	render.Property(os.Stdout, prop.Property)
 
 	return nil
 }
 
 func propertyUpdate(c *cli.Context) error {
-	data := unmarshalProp(c)
-	prop, err := client(c).PropertyUpdate(c.Args().First(), data)
+	data, err := unmarshalProp(c)
+	if err != nil {
+		return err
+	}
+	domain := c.Args().First()
+	api := client(c)
+	submit, err := fileUpdate(c, domain, "property", data.Name, data, func() (interface{}, error) {
+		return api.Property(domain, data.Name)
+	}, func() interface{} { return &edgegrid.Property{} })
+	if err != nil {
+		return err
+	}
+	prop, err := api.PropertyUpdate(domain, submit.(*edgegrid.Property))
 	if err != nil {
 		return err
 	}
 
-	printProp(prop.Property)
+	render.Property(os.Stdout, prop.Property)
 
 	return nil
 }
 
-func unmarshalProp(c *cli.Context) *edgegrid.Property {
+func unmarshalProp(c *cli.Context) (*edgegrid.Property, error) {
 	propSt := &edgegrid.Property{}
-	data, err := ioutil.ReadFile(c.String("json"))
-	if err != nil {
-		panic(err)
-	}
-	if err := json.Unmarshal(data, propSt); err != nil {
-		panic(err)
+	if err := unmarshalInput(c, propSt); err != nil {
+		return nil, err
 	}
 
-	return propSt
+	return propSt, nil
 }
 
 func propertyDelete(c *cli.Context) error {
@@ -591,239 +998,76 @@ func propertyDelete(c *cli.Context) error {
 }
 
 func trafficTargets(c *cli.Context) error {
-	name := c.String("name")
-	prop, err := client(c).Property(c.Args().First(), name)
-	if err != nil {
-		return err
-	}
-
-	for _, target := range prop.TrafficTargets {
-		fmt.Printf("\nTraffic target\n")
-		printTrafficTarget(target)
-	}
-
-	return nil
-}
-
-func printTrafficTarget(target edgegrid.TrafficTarget) error {
-	data := [][]string{
-		[]string{"Name", interfaceToStr(target.Name)},
-		[]string{"DCId", strconv.Itoa(target.DataCenterID)},
-		[]string{"Enabled", strconv.FormatBool(target.Enabled)},
-		[]string{"HandoutCname", interfaceToStr(target.HandoutCname)},
-		[]string{"Servers", strings.Join(target.Servers, ", ")},
-		[]string{"Weight", floatToStr(target.Weight)},
-	}
+	return watched(c, func(w io.Writer, args cli.Args, fields watchFields) error {
+		prop, err := client(c).Property(args.First(), c.String("name"))
+		if err != nil {
+			return err
+		}
 
-	printBasicTable(data)
+		for _, target := range prop.TrafficTargets {
+			rows := render.TrafficTargetRows(target)
+			fields.addRows(strconv.Itoa(target.DataCenterID)+"/", rows)
+			fmt.Fprintf(w, "\nTraffic target\n")
+			render.BasicTable(w, rows)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func livenessTests(c *cli.Context) error {
-	prop := c.String("name")
-	props, err := client(c).Property(c.Args().First(), prop)
+	prop, err := client(c).Property(c.Args().First(), c.String("name"))
 	if err != nil {
 		return err
 	}
 
-	for _, test := range props.LivenessTests {
-		printLivenessTest(test)
+	for _, test := range prop.LivenessTests {
+		render.LivenessTest(os.Stdout, test)
 	}
 
 	return nil
 }
 
-func printLivenessTest(test edgegrid.LivenessTest) error {
-	data := [][]string{
-		[]string{"Name", test.Name},
-		[]string{"HTTPError3xx", strconv.FormatBool(test.HTTPError3xx)},
-		[]string{"HTTPError4xx", strconv.FormatBool(test.HTTPError4xx)},
-		[]string{"HTTPError5xx", strconv.FormatBool(test.HTTPError5xx)},
-		[]string{"TestInterval", strconv.FormatInt(test.TestInterval, 10)},
-		[]string{"TestObject", test.TestObject},
-		[]string{"TestObjectPort", strconv.FormatInt(test.TestObjectPort, 10)},
-		[]string{"TestObjectProtocol", test.TestObjectProtocol},
-		[]string{"TestObjectUsername", test.TestObjectUsername},
-		[]string{"TestObjectPassword", test.TestObjectPassword},
-		[]string{"TestTimeout", floatToStr(test.TestTimeout)},
-		[]string{"DisableNonstandardPortWarning", strconv.FormatBool(test.DisableNonstandardPortWarning)},
-		[]string{"RequestString", test.RequestString},
-		[]string{"ResponseString", test.ResponseString},
-		[]string{"SSLClientPrivateKey", test.SSLClientPrivateKey},
-		[]string{"SSLCertificate", test.SSLCertificate},
-		[]string{"HostHeader", test.HostHeader},
-	}
-
-	printBasicTable(data)
-
-	return nil
-}
-
 func propertiesDelete(c *cli.Context) error {
-	names := strings.Split(c.String("names"), ",")
-	client := client(c)
-	domain := c.Args().First()
-
-	for _, name := range names {
-		_, err := client.PropertyDelete(domain, strings.TrimSpace(name))
-		if err != nil {
-			fmt.Printf("Failed to delete Property: %s\n", name)
-			fmt.Printf("Error is: %v", err)
-
-			return err
-		}
-
-		fmt.Printf("Deleted Property: %s\n", name)
+	names := []string{}
+	for _, name := range strings.Split(c.String("names"), ",") {
+		names = append(names, strings.TrimSpace(name))
 	}
 
-	return nil
+	return gtm.DeleteProperties(client(c), c.Args().First(), names, deleteProgress("Property"))
 }
 
 func propertiesDeleteAll(c *cli.Context) error {
-	domain := c.Args().First()
-	client := client(c)
-	props, err := client.Properties(domain)
-	if err != nil {
-		return err
-	}
+	_, err := gtm.DeleteAllProperties(client(c), c.Args().First(), deleteProgress("Property"))
 
-	for _, prop := range props.Properties {
-		_, err := client.PropertyDelete(domain, prop.Name)
+	return err
+}
+
+func status(c *cli.Context) error {
+	return watched(c, func(w io.Writer, args cli.Args, fields watchFields) error {
+		status, err := client(c).DomainStatus(args.First())
 		if err != nil {
-			fmt.Printf("Failed to delete Property: %s\n", prop.Name)
-			fmt.Printf("Error is: %v", err)
-		} else {
-			fmt.Printf("Deleted Property: %s\n", prop.Name)
+			return err
 		}
-	}
 
-	return nil
+		rows := render.StatusRows(status)
+		fields.addRows("", rows)
+		render.BasicTable(w, rows)
+
+		return nil
+	})
 }
 
-func client(c *cli.Context) *edgegrid.GTMClient {
-	return edgegrid.GTMClientWithCreds(
+// newAPI returns the GTM API the commands and completion use. Tests
+// replace it to run commands against a fake.
+var newAPI = gtm.NewClient
+
+func client(c *cli.Context) gtm.API {
+	api := newAPI(
 		c.GlobalString("access_token"),
 		c.GlobalString("client_token"),
 		c.GlobalString("client_secret"),
 		c.GlobalString("host"))
-}
-
-func targetIds(trafficTargets []edgegrid.TrafficTarget) []string {
-	targets := []string{}
-
-	for _, t := range trafficTargets {
-		targets = append(targets, strconv.Itoa(t.DataCenterID))
-	}
-
-	return targets
-}
-
-func livenessTestNames(livenessTests []edgegrid.LivenessTest) []string {
-	tests := []string{}
-
-	for _, t := range livenessTests {
-		tests = append(tests, t.Name)
-	}
-
-	return tests
-}
-
-func printProp(prop *edgegrid.Property) error {
-	data := [][]string{
-		[]string{"BackupCname", prop.BackupCname},
-		[]string{"BackupIP", prop.BackupIP},
-		[]string{"BalanceByDownloadScore", strconv.FormatBool(prop.BalanceByDownloadScore)},
-		[]string{"Cname", prop.Cname},
-		[]string{"Comments", prop.Comments},
-		[]string{"DynamicTTL", strconv.Itoa(prop.DynamicTTL)},
-		[]string{"FailbackDelay", strconv.Itoa(prop.FailbackDelay)},
-		[]string{"FailoverDelay", strconv.Itoa(prop.FailoverDelay)},
-		[]string{"HandoutMode", prop.HandoutMode},
-		[]string{"HealthMax", floatToStr(prop.HealthMax)},
-		[]string{"HealthMultiplier", floatToStr(prop.HealthMultiplier)},
-		[]string{"HealthThreshold", floatToStr(prop.HealthThreshold)},
-		[]string{"Ipv6", strconv.FormatBool(prop.Ipv6)},
-		[]string{"LastModified", prop.LastModified},
-		[]string{"LivenessTests", strings.Join(livenessTestNames(prop.LivenessTests), ", ")},
-		[]string{"LoadImbalancePercentage", floatToStr(prop.LoadImbalancePercentage)},
-		[]string{"MapName", interfaceToStr(prop.MapName)},
-		[]string{"MaxUnreachablePenalty", interfaceToStr(prop.MaxUnreachablePenalty)},
-		[]string{"MxRecords", maxRecsString(prop.MxRecords)},
-		[]string{"Name", prop.Name},
-		[]string{"ScoreAggregationType", prop.ScoreAggregationType},
-		[]string{"StaticTTL", interfaceToStr(prop.StaticTTL)},
-		[]string{"StickinessBonusConstant", interfaceToStr(prop.StickinessBonusConstant)},
-		[]string{"StickinessBonusPercentage", interfaceToStr(prop.StickinessBonusPercentage)},
-		[]string{"TrafficTargets", strings.Join(targetIds(prop.TrafficTargets), ", ")},
-		[]string{"Type", prop.Type},
-		[]string{"UnreachableThreshold", interfaceToStr(prop.UnreachableThreshold)},
-		[]string{"UseComputedTargets", strconv.FormatBool(prop.UseComputedTargets)},
-	}
-
-	printBasicTable(data)
-
-	return nil
-}
-
-func status(c *cli.Context) error {
-	client := client(c)
-	status, err := client.DomainStatus(c.Args().First())
-	if err != nil {
-		return err
-	}
-	data := [][]string{
-		[]string{"PropagationStatus", status.PropagationStatus},
-		[]string{"PassingValidation", strconv.FormatBool(status.PassingValidation)},
-		[]string{"Message", status.Message},
-		[]string{"ChangeID", status.ChangeID},
-		[]string{"PropagationStatusDate", status.PropagationStatusDate},
-	}
-
-	printBasicTable(data)
-
-	return nil
-}
-
-func printBasicTable(data [][]string) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.AppendBulk(data)
-	table.SetRowLine(true)
-	table.Render()
-}
-
-func printTableWithHeaders(headers []string, data [][]string) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader(headers)
-	table.AppendBulk(data)
-	table.SetRowLine(true)
-	table.Render()
-}
-
-func floatToStr(input float64) string {
-	return strconv.FormatFloat(input, 'f', 6, 64)
-}
-
-func maxRecsString(records []interface{}) string {
-	recs := []string{}
-
-	for _, r := range records {
-		recs = append(recs, r.(string))
-	}
-
-	return strings.Join(recs, ", ")
-}
-
-func interfaceToStr(inter interface{}) string {
-	if str, ok := inter.(string); ok {
-		return str
-	}
-	if num, ok := inter.(int); ok {
-		return strconv.Itoa(num)
-	}
-	if float, ok := inter.(float64); ok {
-		return floatToStr(float)
-	}
 
-	return ""
+	return withHooks(api, c.Command.Name)
 }
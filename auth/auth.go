@@ -0,0 +1,183 @@
+// Package auth implements the Akamai EdgeGrid (EG1-HMAC-SHA256) request
+// signing scheme used by the Luna APIs.
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Algorithm is the EdgeGrid signing algorithm identifier.
+const Algorithm = "EG1-HMAC-SHA256"
+
+// TimestampFormat is the format of the timestamp in a signed request.
+const TimestampFormat = "20060102T15:04:05-0700"
+
+// MaxBody is the number of body bytes included in the content hash.
+const MaxBody = 131072
+
+// ErrUnsigned is returned by Verify when a request has no EdgeGrid
+// Authorization header.
+var ErrUnsigned = errors.New("request is not signed")
+
+// Credentials are the EdgeGrid API client credentials.
+type Credentials struct {
+	ClientToken  string
+	ClientSecret string
+	AccessToken  string
+}
+
+// Sign adds an EdgeGrid Authorization header to req. The request body, if
+// any, is read and restored.
+func Sign(req *http.Request, creds Credentials) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	header := authHeader(creds, time.Now().UTC().Format(TimestampFormat), hex.EncodeToString(nonce))
+	signature := signature(req, body, creds.ClientSecret, header)
+	req.Header.Set("Authorization", header+"signature="+signature)
+
+	return nil
+}
+
+// Verify checks that req carries a valid EdgeGrid signature made with
+// creds, and that its timestamp is within maxSkew of now. The request
+// body, if any, is read and restored.
+func Verify(req *http.Request, creds Credentials, maxSkew time.Duration) error {
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, Algorithm+" ") {
+		return ErrUnsigned
+	}
+
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.TrimPrefix(header, Algorithm+" "), ";") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	for _, name := range []string{"client_token", "access_token", "timestamp", "nonce", "signature"} {
+		if fields[name] == "" {
+			return fmt.Errorf("authorization header has no %s", name)
+		}
+	}
+	if fields["client_token"] != creds.ClientToken || fields["access_token"] != creds.AccessToken {
+		return errors.New("unknown client or access token")
+	}
+
+	ts, err := time.Parse(TimestampFormat, fields["timestamp"])
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %v", err)
+	}
+	if skew := time.Since(ts); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("timestamp %s is outside the allowed skew", fields["timestamp"])
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	unsigned := header[:strings.Index(header, "signature=")]
+	expected := signature(req, body, creds.ClientSecret, unsigned)
+	if !hmac.Equal([]byte(expected), []byte(fields["signature"])) {
+		return errors.New("signature does not match")
+	}
+
+	return nil
+}
+
+func authHeader(creds Credentials, timestamp, nonce string) string {
+	return fmt.Sprintf("%s client_token=%s;access_token=%s;timestamp=%s;nonce=%s;",
+		Algorithm, creds.ClientToken, creds.AccessToken, timestamp, nonce)
+}
+
+// signature computes the signature of a request whose Authorization header,
+// up to but excluding the signature, is header.
+func signature(req *http.Request, body []byte, secret, header string) string {
+	timestamp := ""
+	for _, field := range strings.Split(header, ";") {
+		if i := strings.Index(field, "timestamp="); i >= 0 {
+			timestamp = field[i+len("timestamp="):]
+		}
+	}
+	key := hmacBase64([]byte(secret), timestamp)
+
+	scheme := "https"
+	if req.URL.Scheme != "" {
+		scheme = req.URL.Scheme
+	} else if req.TLS == nil {
+		scheme = "http"
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+
+	data := strings.Join([]string{
+		strings.ToUpper(req.Method),
+		strings.ToLower(scheme),
+		strings.ToLower(host),
+		path,
+		"", // no headers are included in the signature
+		contentHash(req.Method, body),
+		header,
+	}, "\t")
+
+	return hmacBase64([]byte(key), data)
+}
+
+func contentHash(method string, body []byte) string {
+	if method != "POST" || len(body) == 0 {
+		return ""
+	}
+	if len(body) > MaxBody {
+		body = body[:MaxBody]
+	}
+	sum := sha256.Sum256(body)
+
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func hmacBase64(key []byte, data string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
@@ -0,0 +1,956 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/akamai-gtm/gtm/gtmfake"
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+const testDomain = "example.akadns.net"
+
+// newTestFake returns a domain with data centers east (3131) and west
+// (3132), both used by property www, and spare (3133), used by nothing.
+func newTestFake() *gtmfake.Client {
+	return gtmfake.New(edgegrid.Domain{
+		Name: testDomain,
+		Type: "weighted",
+		Datacenters: []edgegrid.DataCenter{
+			{DataCenterID: 3131, Nickname: "east", City: "Philadelphia"},
+			{DataCenterID: 3132, Nickname: "west", City: "Denver"},
+			{DataCenterID: 3133, Nickname: "spare"},
+		},
+		Properties: []edgegrid.Property{{
+			Name:                 "www",
+			Type:                 "weighted-round-robin",
+			HandoutMode:          "normal",
+			ScoreAggregationType: "mean",
+			TrafficTargets: []edgegrid.TrafficTarget{
+				{DataCenterID: 3131, Enabled: true, Weight: 3, Servers: []string{"192.0.2.1"}},
+				{DataCenterID: 3132, Enabled: true, Weight: 1, Servers: []string{"192.0.2.2"}},
+			},
+			LivenessTests: []edgegrid.LivenessTest{
+				{Name: "health", TestObjectProtocol: "HTTP", TestObject: "/health", TestObjectPort: 80, TestInterval: 60},
+			},
+		}},
+	})
+}
+
+// runCommand runs the CLI with args against fake, with its state in dir,
+// and returns what it wrote to stdout and stderr. Prompts fail as if stdin were not a
+// terminal.
+func runCommand(t *testing.T, fake gtm.API, dir string, args ...string) (string, error) {
+	return runCommandWithInput(t, fake, dir, "", args...)
+}
+
+// runCommandWithInput is runCommand with prompts answered from input,
+// unless it is empty.
+func runCommandWithInput(t *testing.T, fake gtm.API, dir, input string, args ...string) (string, error) {
+	defer func(f func(string, string, string, string) gtm.API) { newAPI = f }(newAPI)
+	newAPI = func(string, string, string, string) gtm.API { return fake }
+	defer func(r *bufio.Reader, isTerminal func() bool) { stdin, stdinIsTerminal = r, isTerminal }(stdin, stdinIsTerminal)
+	stdin = bufio.NewReader(strings.NewReader(input))
+	stdinIsTerminal = func() bool { return input != "" }
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	out := make(chan string)
+	go func() {
+		buf := &bytes.Buffer{}
+		io.Copy(buf, r)
+		out <- buf.String()
+	}()
+
+	err = newApp().Run(append([]string{"akamai-gtm", "--state-dir", filepath.Join(dir, "state")}, args...))
+	w.Close()
+	os.Stdout, os.Stderr = stdout, stderr
+
+	return <-out, err
+}
+
+// writeFile writes a file for a command to read and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are written to the test's directory; "{dir}" in args is
+		// replaced with its path.
+		files map[string]string
+		// setup prepares the fake beyond newTestFake.
+		setup func(t *testing.T, fake *gtmfake.Client)
+		// api, if set, wraps the fake for the command.
+		api  func(fake *gtmfake.Client) gtm.API
+		args []string
+		// input answers the command's prompts.
+		input string
+		want  []string
+		// dontWant must not be in the output.
+		dontWant []string
+		wantErr  string
+		// env is set for the command; "{dir}" in values is replaced too.
+		env map[string]string
+		// wantFiles are the files the command should write, relative to
+		// the test's directory.
+		wantFiles []string
+		check     func(t *testing.T, fake *gtmfake.Client)
+	}{
+		{
+			name: "domains",
+			args: []string{"domains"},
+			want: []string{testDomain + "\n"},
+		},
+		{
+			name: "domain",
+			args: []string{"domain", testDomain},
+			want: []string{testDomain, "weighted"},
+		},
+		{
+			name: "domain create",
+			args: []string{"domain-create", "--type", "basic", "new.akadns.net"},
+			want: []string{"Created new.akadns.net"},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				dom, err := fake.Domain("new.akadns.net")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if dom.Type != "basic" {
+					t.Errorf("type = %s, want basic", dom.Type)
+				}
+			},
+		},
+		{
+			name: "domain create from a definition",
+			files: map[string]string{"domain.yaml": `name: new.akadns.net
+type: full
+datacenters:
+- datacenterId: 3131
+  nickname: east
+geographicMaps:
+- name: geo
+  defaultDatacenter:
+    datacenterId: 3131
+`},
+			args: []string{"domain-create", "--file", "{dir}/domain.yaml", "--contract", "C-1", "--group", "42"},
+			want: []string{"Created new.akadns.net"},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if !called(fake, "DomainCreateFrom new.akadns.net contract=C-1 group=42") {
+					t.Errorf("domain not created in the contract and group: %q", fake.Calls)
+				}
+				doc, err := fake.DomainDocument("new.akadns.net")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if doc["type"] != "full" || len(doc["datacenters"].([]interface{})) != 1 || len(doc["geographicMaps"].([]interface{})) != 1 {
+					t.Errorf("domain = %v, want a full domain with a data center and a map", doc)
+				}
+			},
+		},
+		{
+			name:    "domain create name mismatch",
+			files:   map[string]string{"domain.yaml": "name: other.akadns.net\ntype: full\n"},
+			args:    []string{"domain-create", "--file", "{dir}/domain.yaml", "--contract", "C-1", "new.akadns.net"},
+			wantErr: "the Domain is named other.akadns.net in",
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				for _, name := range []string{"new.akadns.net", "other.akadns.net"} {
+					if _, err := fake.Domain(name); err == nil {
+						t.Errorf("%s was created", name)
+					}
+				}
+			},
+		},
+		{
+			name:  "contracts",
+			setup: withIdentity,
+			args:  []string{"contracts"},
+			want:  []string{"C-1", "Main", "C-2", "Other"},
+		},
+		{
+			name: "no contracts",
+			args: []string{"contracts"},
+			want: []string{"No contracts found"},
+		},
+		{
+			name:     "groups of a contract",
+			setup:    withIdentity,
+			args:     []string{"groups", "--contract", "C-2"},
+			want:     []string{"43", "Staging", "C-1, C-2"},
+			dontWant: []string{"Production"},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if !called(fake, "Groups") {
+					t.Error("groups were not listed")
+				}
+			},
+		},
+		{
+			name: "domain patch",
+			args: []string{"domain-patch", "--set", "type=full", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if dom, _ := fake.Domain(testDomain); dom == nil || dom.Type != "full" {
+					t.Errorf("domain = %+v, want type full", dom)
+				}
+			},
+		},
+		{
+			name:  "domain update",
+			files: map[string]string{"domain.yaml": "name: " + testDomain + "\ntype: full\n"},
+			args:  []string{"domain-update", "--file", "{dir}/domain.yaml"},
+			want:  []string{"Updated domain: " + testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				dom, err := fake.Domain(testDomain)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if dom.Type != "full" {
+					t.Errorf("type = %s, want full", dom.Type)
+				}
+			},
+		},
+		{
+			name: "data centers",
+			args: []string{"data-centers", testDomain},
+			want: []string{"east", "3131", "west", "3132", "spare", "3133"},
+		},
+		{
+			name: "data center",
+			args: []string{"data-center", "--id", "3132", testDomain},
+			want: []string{"west", "Denver"},
+		},
+		{
+			name:    "data center not found",
+			args:    []string{"data-center", "--id", "9999", testDomain},
+			wantErr: "data center 9999 not found",
+		},
+		{
+			name:  "data center create",
+			files: map[string]string{"dc.yaml": "nickname: north\ncity: Toronto\n"},
+			args:  []string{"data-center-create", "--file", "{dir}/dc.yaml", testDomain},
+			want:  []string{"Created north"},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				dc := dataCenterNamed(t, fake, "north")
+				if dc == nil || dc.City != "Toronto" {
+					t.Errorf("data center north = %+v, want one in Toronto", dc)
+				}
+			},
+		},
+		{
+			name:  "data center update",
+			files: map[string]string{"dc.yaml": "datacenterId: 3132\nnickname: west\ncity: Boulder\n"},
+			args:  []string{"data-center-update", "--file", "{dir}/dc.yaml", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if dc, _ := fake.DataCenter(testDomain, 3132); dc == nil || dc.City != "Boulder" {
+					t.Errorf("data center 3132 = %+v, want one in Boulder", dc)
+				}
+			},
+		},
+		{
+			name: "data center patch",
+			args: []string{"data-center-patch", "--id", "3131", "--set", "city=Pittsburgh", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if dc, _ := fake.DataCenter(testDomain, 3131); dc == nil || dc.City != "Pittsburgh" {
+					t.Errorf("data center 3131 = %+v, want one in Pittsburgh", dc)
+				}
+			},
+		},
+		{
+			name:    "data center delete in use",
+			args:    []string{"data-center-delete", "--id", "3131", testDomain},
+			wantErr: "still in use",
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if _, err := fake.DataCenter(testDomain, 3131); err != nil {
+					t.Errorf("data center 3131 was deleted: %v", err)
+				}
+			},
+		},
+		{
+			name: "data center delete forced",
+			args: []string{"data-center-delete", "--force", "--id", "3131", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if _, err := fake.DataCenter(testDomain, 3131); err == nil {
+					t.Error("data center 3131 was not deleted")
+				}
+			},
+		},
+		{
+			name: "data center delete unused",
+			args: []string{"data-center-delete", "--id", "3133", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if _, err := fake.DataCenter(testDomain, 3133); err == nil {
+					t.Error("data center 3133 was not deleted")
+				}
+			},
+		},
+		{
+			name:    "data centers delete in use",
+			args:    []string{"data-centers-delete", "--id", "3133", "--id", "3131", testDomain},
+			wantErr: "still in use",
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if dcs, _ := fake.DataCenters(testDomain); len(dcs) != 3 {
+					t.Errorf("%d data centers left, want 3", len(dcs))
+				}
+			},
+		},
+		{
+			name: "data centers delete forced",
+			args: []string{"data-centers-delete", "--id", "3133", "--id", "3131", "--force", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				dcs, _ := fake.DataCenters(testDomain)
+				if len(dcs) != 1 || dcs[0].DataCenterID != 3132 {
+					t.Errorf("data centers left = %+v, want only 3132", dcs)
+				}
+			},
+		},
+		{
+			name:    "data centers delete all in use",
+			args:    []string{"data-centers-delete-all", testDomain},
+			wantErr: "still in use",
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if dcs, _ := fake.DataCenters(testDomain); len(dcs) != 3 {
+					t.Errorf("%d data centers left, want 3", len(dcs))
+				}
+			},
+		},
+		{
+			name: "data center usage",
+			args: []string{"data-center-usage", "--id", "3131", testDomain},
+			want: []string{"www", "traffic target 0 (enabled)"},
+		},
+		{
+			name: "properties",
+			args: []string{"properties", testDomain},
+			want: []string{"www", "weighted-round-robin"},
+		},
+		{
+			name: "property",
+			args: []string{"property", "--name", "www", testDomain},
+			want: []string{"www", "weighted-round-robin"},
+		},
+		{
+			name: "property create",
+			files: map[string]string{"prop.yaml": `name: api
+type: failover
+scoreAggregationType: mean
+trafficTargets:
+- datacenterId: 3133
+  enabled: true
+  weight: 1
+  servers: [192.0.2.3]
+`},
+			args: []string{"property-create", "--file", "{dir}/prop.yaml", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				prop, err := fake.Property(testDomain, "api")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if prop.Type != "failover" || len(prop.TrafficTargets) != 1 {
+					t.Errorf("property api = %+v", prop)
+				}
+			},
+		},
+		{
+			name: "property patch",
+			args: []string{"property-patch", "--name", "www", "--set", "handoutMode=one-ip", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if prop, _ := fake.Property(testDomain, "www"); prop == nil || prop.HandoutMode != "one-ip" {
+					t.Errorf("property www = %+v, want handout mode one-ip", prop)
+				}
+			},
+		},
+		{
+			name: "property delete",
+			args: []string{"property-delete", "--name", "www", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if _, err := fake.Property(testDomain, "www"); err == nil {
+					t.Error("property www was not deleted")
+				}
+			},
+		},
+		{
+			name:    "properties delete",
+			args:    []string{"properties-delete", "--names", "www, nosuch", testDomain},
+			wantErr: "nosuch",
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if _, err := fake.Property(testDomain, "www"); err == nil {
+					t.Error("property www was not deleted")
+				}
+			},
+		},
+		{
+			name: "properties delete all",
+			args: []string{"properties-delete-all", testDomain},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if props, _ := fake.Properties(testDomain); len(props.Properties) != 0 {
+					t.Errorf("%d properties left, want none", len(props.Properties))
+				}
+			},
+		},
+		{
+			name: "traffic targets",
+			args: []string{"traffic-targets", "--name", "www", testDomain},
+			want: []string{"3131", "192.0.2.1", "3132", "192.0.2.2"},
+		},
+		{
+			name: "liveness tests",
+			args: []string{"liveness-tests", "--name", "www", testDomain},
+			want: []string{"health", "/health"},
+		},
+		{
+			name: "status",
+			args: []string{"status", testDomain},
+			want: []string{"COMPLETE"},
+		},
+		{
+			name: "weights",
+			args: []string{"weights", "--name", "www", "--samples", "1000", "--seed", "1", testDomain},
+			want: []string{"75.00%", "25.00%", "Sampled 1000 queries (seed 1)"},
+		},
+		{
+			name: "simulate",
+			args: []string{"simulate", "--name", "www", "--down", "3131", testDomain},
+			want: []string{"192.0.2.2", "100.00%"},
+		},
+		{
+			name: "export",
+			args: []string{"export", "--dir", "{dir}/export", testDomain},
+			wantFiles: []string{
+				"export/domain.json",
+				"export/datacenters/3131.json",
+				"export/datacenters/3132.json",
+				"export/datacenters/3133.json",
+				"export/properties/www.json",
+			},
+		},
+		{
+			name: "search",
+			args: []string{"search", "192.0.2.2"},
+			want: []string{testDomain, "www", "192.0.2.2"},
+		},
+		{
+			name:     "search exact",
+			args:     []string{"search", "--exact", "192.0.2"},
+			want:     []string{"No matches found for: 192.0.2"},
+			dontWant: []string{"www"},
+		},
+		{
+			name:  "render",
+			files: map[string]string{"dc.json": `{"nickname": "{{ .name }}", "city": "{{ .city }}"}`},
+			args:  []string{"render", "--file", "{dir}/dc.json", "--set", "name=north", "--set", "city=Oslo"},
+			want:  []string{`"nickname": "north"`, `"city": "Oslo"`},
+		},
+		{
+			name:    "render missing variable",
+			files:   map[string]string{"dc.json": `{"nickname": "{{ .name }}"}`},
+			args:    []string{"render", "--file", "{dir}/dc.json"},
+			wantErr: "name",
+		},
+		{
+			name:  "edit domain",
+			files: map[string]string{"editor.sh": editTypeScript},
+			env:   map[string]string{"VISUAL": "", "EDITOR": "sh {dir}/editor.sh"},
+			args:  []string{"edit", "domain", "--yes", testDomain},
+			want:  []string{`-  "type": "weighted"`, `+  "type": "full"`},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if dom, _ := fake.Domain(testDomain); dom == nil || dom.Type != "full" {
+					t.Errorf("domain = %+v, want type full", dom)
+				}
+			},
+		},
+		{
+			name:    "edit domain declined",
+			files:   map[string]string{"editor.sh": editTypeScript},
+			env:     map[string]string{"VISUAL": "", "EDITOR": "sh {dir}/editor.sh"},
+			args:    []string{"edit", "domain", testDomain},
+			input:   "n\n",
+			wantErr: "not updated",
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				if dom, _ := fake.Domain(testDomain); dom == nil || dom.Type != "weighted" {
+					t.Errorf("domain = %+v, want type weighted", dom)
+				}
+			},
+		},
+		{
+			name:  "lint sarif lists custom rules",
+			files: map[string]string{"lint.yaml": lintConfigYAML},
+			args:  []string{"lint", "--config", "{dir}/lint.yaml", "--format", "sarif", "--fail-on", "none", testDomain},
+			want: []string{
+				`"id": "weighted-www"`,
+				`"text": "www is weighted"`,
+				`"level": "note"`,
+				`"ruleId": "weighted-www"`,
+			},
+		},
+		{
+			name:    "lint fails on custom rule",
+			files:   map[string]string{"lint.yaml": lintConfigYAML},
+			args:    []string{"lint", "--config", "{dir}/lint.yaml", "--fail-on", "info", testDomain},
+			want:    []string{"weighted-www", "www is weighted"},
+			wantErr: "at or above severity info",
+		},
+		{
+			name:     "data centers import dry run",
+			files:    map[string]string{"dcs.csv": importCSV},
+			args:     []string{"data-centers-import", "--csv", "{dir}/dcs.csv", "--dry-run", testDomain},
+			want:     []string{"update", `City: "Philadelphia" ->`, "create", `City: "Oslo"`, "unchanged"},
+			dontWant: []string{"created", "updated"},
+			check:    importApplied(false),
+		},
+		{
+			name:    "data centers import not confirmed",
+			files:   map[string]string{"dcs.csv": importCSV},
+			args:    []string{"data-centers-import", "--csv", "{dir}/dcs.csv", testDomain},
+			wantErr: "stdin is not a terminal: use --yes",
+			check:   importApplied(false),
+		},
+		{
+			name:    "data centers import declined",
+			files:   map[string]string{"dcs.csv": importCSV},
+			args:    []string{"data-centers-import", "--csv", "{dir}/dcs.csv", testDomain},
+			input:   "n\n",
+			wantErr: "nothing was imported",
+			check:   importApplied(false),
+		},
+		{
+			name:  "data centers import confirmed",
+			files: map[string]string{"dcs.csv": importCSV},
+			args:  []string{"data-centers-import", "--csv", "{dir}/dcs.csv", testDomain},
+			input: "y\n",
+			want:  []string{"updated", "created 3134"},
+			check: importApplied(true),
+		},
+		{
+			name:  "data centers import without created data center",
+			files: map[string]string{"dcs.csv": importCSV},
+			api:   func(fake *gtmfake.Client) gtm.API { return noCreatedDataCenter{fake} },
+			args:  []string{"data-centers-import", "--csv", "{dir}/dcs.csv", "--yes", testDomain},
+			want:  []string{"updated", "| created   |"},
+		},
+		{
+			name:    "data centers import invalid",
+			files:   map[string]string{"dcs.csv": "Nickname,Latitude\neast,north\n"},
+			args:    []string{"data-centers-import", "--csv", "{dir}/dcs.csv", "--yes", testDomain},
+			want:    []string{"Latitude: invalid number"},
+			wantErr: "1 invalid rows; nothing was imported",
+		},
+		{
+			name:    "data centers import unknown column",
+			files:   map[string]string{"dcs.csv": "Nickname,Color\neast,red\n"},
+			args:    []string{"data-centers-import", "--csv", "{dir}/dcs.csv", "--yes", testDomain},
+			wantErr: `unknown column "Color"`,
+		},
+		{
+			name:    "domain delete not empty",
+			args:    []string{"domain-delete", "--confirm", testDomain, "--backup", "{dir}/backup.json", testDomain},
+			want:    []string{"properties", "www", "datacenters", "east, spare, west"},
+			wantErr: "is not empty",
+			check:   domainExists(true),
+		},
+		{
+			name:    "domain delete not confirmed",
+			args:    []string{"domain-delete", "--backup", "{dir}/backup.json", "--cascade", testDomain},
+			wantErr: "stdin is not a terminal: use --confirm <domain>",
+			check:   domainExists(true),
+		},
+		{
+			name:    "domain delete wrong confirmation",
+			args:    []string{"domain-delete", "--confirm", "other.akadns.net", "--backup", "{dir}/backup.json", "--cascade", testDomain},
+			wantErr: "does not match",
+			check:   domainExists(true),
+		},
+		{
+			name:    "domain delete wrong name typed",
+			args:    []string{"domain-delete", "--backup", "{dir}/backup.json", "--cascade", testDomain},
+			input:   "example\n",
+			wantErr: `"example" does not match`,
+			check:   domainExists(true),
+		},
+		{
+			name:      "domain delete cascade",
+			setup:     withMaps,
+			args:      []string{"domain-delete", "--backup", "{dir}/backup.json", "--cascade", testDomain},
+			input:     testDomain + "\n",
+			want:      []string{"Saved backup", "Deleted geographic-map geo", "Deleted resource pool", "Submitted deletion of " + testDomain},
+			wantFiles: []string{"backup.json"},
+			check: func(t *testing.T, fake *gtmfake.Client) {
+				domainExists(false)(t, fake)
+				deletes := []string{}
+				for _, call := range fake.Calls {
+					if strings.Contains(call, "Delete ") {
+						deletes = append(deletes, strings.TrimSpace(call))
+					}
+				}
+				want := []string{
+					"PropertyDelete example.akadns.net www",
+					"ObjectDelete example.akadns.net geographic-maps geo",
+					"ObjectDelete example.akadns.net resources pool",
+					"DataCenterDelete example.akadns.net 3131",
+					"DataCenterDelete example.akadns.net 3132",
+					"DataCenterDelete example.akadns.net 3133",
+					"DomainDelete example.akadns.net",
+				}
+				if !reflect.DeepEqual(deletes, want) {
+					t.Errorf("deletes = %q, want %q", deletes, want)
+				}
+			},
+		},
+		{
+			name:  "domain delete wait",
+			args:  []string{"domain-delete", "--confirm", testDomain, "--backup", "{dir}/backup.json", "--wait", "1m", "--cascade", testDomain},
+			want:  []string{"waiting for it to be confirmed", "Deleted " + testDomain},
+			check: domainExists(false),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "akamai-gtm-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for name, content := range test.files {
+				writeFile(t, dir, name, content)
+			}
+			args := []string{}
+			for _, arg := range test.args {
+				args = append(args, strings.Replace(arg, "{dir}", dir, -1))
+			}
+			for name, value := range test.env {
+				defer os.Setenv(name, os.Getenv(name))
+				os.Setenv(name, strings.Replace(value, "{dir}", dir, -1))
+			}
+
+			fake := newTestFake()
+			if test.setup != nil {
+				test.setup(t, fake)
+			}
+			api := gtm.API(fake)
+			if test.api != nil {
+				api = test.api(fake)
+			}
+			out, err := runCommandWithInput(t, api, dir, test.input, args...)
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Fatalf("error = %v\noutput:\n%s", err, out)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Fatalf("error = %v, want one containing %q", err, test.wantErr)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output does not contain %q:\n%s", want, out)
+				}
+			}
+			for _, dontWant := range test.dontWant {
+				if strings.Contains(out, dontWant) {
+					t.Errorf("output contains %q:\n%s", dontWant, out)
+				}
+			}
+			for _, name := range test.wantFiles {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("%s was not written: %v", name, err)
+				}
+			}
+			if test.check != nil {
+				test.check(t, fake)
+			}
+		})
+	}
+}
+
+// TestHistoryRollback updates a data center, then rolls it back and
+// forward again through the local history.
+func TestHistoryRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "akamai-gtm-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fake := newTestFake()
+
+	steps := []struct {
+		args []string
+		want string
+		city string
+	}{
+		{[]string{"--comment", "move", "data-center-patch", "--id", "3132", "--set", "city=Boulder", testDomain}, "", "Boulder"},
+		{[]string{"history", testDomain}, "update datacenter:3132", "Boulder"},
+		{[]string{"history-diff", testDomain, "1"}, `+  "city": "Boulder"`, "Boulder"},
+		{[]string{"rollback", "--to", "1^", "--yes", testDomain}, "Rolled datacenter:3132 back to version 1^", "Denver"},
+		{[]string{"rollback", "--to", "1", "--yes", testDomain}, "Rolled datacenter:3132 back to version 1", "Boulder"},
+		{[]string{"rollback", "--to", "1", "--yes", testDomain}, "datacenter:3132 is already as at version 1", "Boulder"},
+	}
+	for _, step := range steps {
+		out, err := runCommand(t, fake, dir, step.args...)
+		if err != nil {
+			t.Fatalf("%s: %v\noutput:\n%s", strings.Join(step.args, " "), err, out)
+		}
+		if !strings.Contains(out, step.want) {
+			t.Errorf("%s: output does not contain %q:\n%s", strings.Join(step.args, " "), step.want, out)
+		}
+		if dc, _ := fake.DataCenter(testDomain, 3132); dc == nil || dc.City != step.city {
+			t.Errorf("%s: data center 3132 = %+v, want one in %s", strings.Join(step.args, " "), dc, step.city)
+		}
+	}
+
+	out, err := runCommand(t, fake, dir, "history", testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "datacenter:3132") {
+			versions = append(versions, strings.TrimSpace(strings.Split(line, "|")[6]))
+		}
+	}
+	want := []string{"move", "rollback to version 1^", "rollback to version 1"}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("history comments = %q, want %q", versions, want)
+	}
+}
+
+// TestRollbackRecreatesDataCenter rolls back the deletion of a data
+// center, which recreates it.
+func TestRollbackRecreatesDataCenter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "akamai-gtm-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fake := newTestFake()
+
+	if out, err := runCommand(t, fake, dir, "data-center-delete", "--id", "3133", testDomain); err != nil {
+		t.Fatalf("%v\noutput:\n%s", err, out)
+	}
+	out, err := runCommand(t, fake, dir, "rollback", "--to", "1^", "--yes", testDomain)
+	if err != nil {
+		t.Fatalf("%v\noutput:\n%s", err, out)
+	}
+	if !strings.Contains(out, "recreated it") {
+		t.Errorf("output does not say the data center was recreated:\n%s", out)
+	}
+	if dataCenterNamed(t, fake, "spare") == nil {
+		t.Error("data center spare was not recreated")
+	}
+
+	// failing to look up the domain is not mistaken for the data center
+	// having been deleted
+	fake.Errors = map[string]error{"DomainDocument": io.ErrUnexpectedEOF}
+	before := len(fake.Calls)
+	if _, err := runCommand(t, fake, dir, "rollback", "--to", "1^", "--yes", testDomain); err != io.ErrUnexpectedEOF {
+		t.Errorf("error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	for _, call := range fake.Calls[before:] {
+		if strings.HasPrefix(call, "DataCenterCreate") {
+			t.Errorf("data center created after a failed lookup: %s", call)
+		}
+	}
+}
+
+// importCSV updates east, creates north and leaves west unchanged. Its
+// DataCenterID column, as written by data-centers --csv, is read-only.
+// editTypeScript is an $EDITOR changing a domain's type from weighted to
+// full.
+const editTypeScript = `sed -i.orig 's/^type: weighted$/type: full/' "$1"
+`
+
+// lintConfigYAML adds a custom rule that matches property www.
+const lintConfigYAML = `custom:
+- id: weighted-www
+  object: property
+  severity: info
+  message: www is weighted
+  when:
+  - Type = weighted-round-robin
+`
+
+const importCSV = `Nickname,DataCenterID,City
+east,3131,Boston
+north,,Oslo
+west,3132,
+`
+
+// importApplied checks whether importCSV was applied.
+func importApplied(want bool) func(t *testing.T, fake *gtmfake.Client) {
+	return func(t *testing.T, fake *gtmfake.Client) {
+		east, _ := fake.DataCenter(testDomain, 3131)
+		north := dataCenterNamed(t, fake, "north")
+		if applied := east.City == "Boston" && north != nil && north.City == "Oslo"; applied != want {
+			t.Errorf("east = %+v, north = %+v, want applied = %v", east, north, want)
+		}
+		if west, _ := fake.DataCenter(testDomain, 3132); west.City != "Denver" {
+			t.Errorf("west = %+v, want it unchanged", west)
+		}
+	}
+}
+
+// noCreatedDataCenter is an API whose DataCenterCreate does not return the
+// created data center.
+type noCreatedDataCenter struct {
+	*gtmfake.Client
+}
+
+func (n noCreatedDataCenter) DataCenterCreate(domain string, dc *edgegrid.DataCenter) (*edgegrid.DataCenterResponse, error) {
+	resp, err := n.Client.DataCenterCreate(domain, dc)
+	if resp != nil {
+		resp.DataCenter = nil
+	}
+	return resp, err
+}
+
+// notDeleting is an API whose PropertyDelete reports that nothing was
+// deleted.
+type notDeleting struct {
+	*gtmfake.Client
+}
+
+func (n notDeleting) PropertyDelete(domain, name string) (bool, error) {
+	return false, nil
+}
+
+// failingAfterDelete is an API that cannot be reached once a domain has
+// been deleted.
+type failingAfterDelete struct {
+	*gtmfake.Client
+	deleted bool
+}
+
+func (f *failingAfterDelete) DomainDelete(name string) (*edgegrid.DomainStatus, error) {
+	f.deleted = true
+	return f.Client.DomainDelete(name)
+}
+
+func (f *failingAfterDelete) DomainDocument(name string) (map[string]interface{}, error) {
+	if f.deleted {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return f.Client.DomainDocument(name)
+}
+
+func TestDomainDeleteFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		api     func(fake *gtmfake.Client) gtm.API
+		args    []string
+		wantErr string
+		exists  bool
+	}{
+		{
+			name:    "property not deleted",
+			api:     func(fake *gtmfake.Client) gtm.API { return notDeleting{fake} },
+			args:    []string{"domain-delete", "--confirm", testDomain, "--backup", "{dir}/backup.json", "--cascade", testDomain},
+			wantErr: "failed to delete property www: the API did not delete it; the backup is in",
+			exists:  true,
+		},
+		{
+			name:    "wait fails",
+			api:     func(fake *gtmfake.Client) gtm.API { return &failingAfterDelete{Client: fake} },
+			args:    []string{"domain-delete", "--confirm", testDomain, "--backup", "{dir}/backup.json", "--wait", "1m", "--cascade", testDomain},
+			wantErr: "waiting for the deletion of example.akadns.net to be confirmed: unexpected EOF",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "akamai-gtm-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			args := []string{}
+			for _, arg := range test.args {
+				args = append(args, strings.Replace(arg, "{dir}", dir, -1))
+			}
+
+			fake := newTestFake()
+			out, err := runCommand(t, test.api(fake), dir, args...)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("error = %v, want one containing %q\noutput:\n%s", err, test.wantErr, out)
+			}
+			domainExists(test.exists)(t, fake)
+			for _, call := range fake.Calls {
+				if test.exists && strings.HasPrefix(call, "DataCenterDelete") {
+					t.Errorf("data centers deleted after a failure: %s", call)
+				}
+			}
+		})
+	}
+}
+
+// withIdentity gives the API client two contracts and two groups.
+func withIdentity(t *testing.T, fake *gtmfake.Client) {
+	fake.AvailableContracts = []gtm.Contract{{ContractID: "C-1", ContractName: "Main"}, {ContractID: "C-2", ContractName: "Other"}}
+	fake.AvailableGroups = []gtm.Group{
+		{GroupID: 42, GroupName: "Production", ContractIDs: []string{"C-1"}},
+		{GroupID: 43, GroupName: "Staging", ContractIDs: []string{"C-1", "C-2"}},
+	}
+}
+
+// called reports whether the fake recorded a call, ignoring trailing
+// spaces.
+func called(fake *gtmfake.Client, call string) bool {
+	for _, c := range fake.Calls {
+		if strings.TrimSpace(c) == call {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withMaps adds a geographic map and a resource to the domain.
+func withMaps(t *testing.T, fake *gtmfake.Client) {
+	fields := map[string]interface{}{
+		"geographicMaps": []interface{}{map[string]interface{}{
+			"name":              "geo",
+			"defaultDatacenter": map[string]interface{}{"datacenterId": 3131},
+		}},
+		"resources": []interface{}{map[string]interface{}{"name": "pool", "type": "XML load object via HTTP"}},
+	}
+	for field, value := range fields {
+		if err := fake.SetDocumentField(testDomain, field, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// domainExists checks whether the test domain exists.
+func domainExists(want bool) func(t *testing.T, fake *gtmfake.Client) {
+	return func(t *testing.T, fake *gtmfake.Client) {
+		_, err := fake.Domain(testDomain)
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", testDomain, exists, want)
+		}
+	}
+}
+
+func dataCenterNamed(t *testing.T, fake *gtmfake.Client, nickname string) *edgegrid.DataCenter {
+	dcs, err := fake.DataCenters(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dc := range dcs {
+		if dc.Nickname == nickname {
+			return &dc
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/urfave/cli"
+)
+
+// completionTTL is how long completion candidates fetched from the API are
+// cached.
+const completionTTL = 2 * time.Minute
+
+// completeFiles is printed in place of candidates when the shell should
+// complete file names.
+const completeFiles = ":files"
+
+// completionFileFlags are the flags whose values are paths.
+var completionFileFlags = map[string]bool{
+	"file": true, "json": true, "dir": true, "vars": true, "config": true,
+	"merge-patch": true, "json-patch": true, "tls-cert": true, "tls-key": true,
+	"cert-out": true,
+}
+
+// completionValues are the fixed values of enumerated flags.
+var completionValues = map[string][]string{
+	"type":    {"basic", "full", "weighted", "static", "failover-only"},
+	"fail-on": {"error", "warning", "info"},
+}
+
+// completionArgs are the fixed values of the arguments of commands that do
+// not take a domain.
+var completionArgs = map[string][]string{
+	"completion":  {"bash", "zsh", "fish"},
+	"search":      nil,
+	"render":      nil,
+	"mock-server": nil,
+}
+
+// candidate is a completion candidate with an optional description.
+type candidate struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+func completion(c *cli.Context) error {
+	shell := c.Args().First()
+	script, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("unknown shell %q: expected bash, zsh or fish", shell)
+	}
+
+	name := c.App.Name
+	tmpl := template.Must(template.New(shell).Parse(script))
+
+	return tmpl.Execute(os.Stdout, map[string]string{
+		"Name":     name,
+		"Function": "_" + strings.Replace(name, "-", "_", -1),
+	})
+}
+
+// complete prints the candidates for one of the words of a command line,
+// one per line as <value>\t<description>. Its arguments are the index of
+// the word being completed followed by the words after the program name,
+// so that arguments after the cursor, typically the domain, are known.
+// Errors are ignored, as there is nowhere to report them while completing.
+func complete(c *cli.Context) error {
+	// without flag parsing, urfave/cli passes the command name and a "--"
+	// separator through as arguments
+	args := []string(c.Args())
+	if len(args) > 0 && args[0] == c.Command.Name {
+		args = args[1:]
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	index, err := strconv.Atoi(args[0])
+	words := args[1:]
+	if err != nil || index < 0 || index > len(words) {
+		return nil
+	}
+	if index == len(words) {
+		words = append(words, "")
+	}
+
+	for _, cand := range completeWords(c, words, index) {
+		if cand.Description == "" {
+			fmt.Println(cand.Value)
+		} else {
+			fmt.Printf("%s\t%s\n", cand.Value, cand.Description)
+		}
+	}
+
+	return nil
+}
+
+// completionLine is a partially typed command line.
+type completionLine struct {
+	globals    map[string]string
+	command    *cli.Command
+	flags      map[string]string
+	positional []string
+	// pending is the flag whose value is being typed, if any.
+	pending string
+	current string
+	// beforeCommand is set when the word being typed precedes the command.
+	beforeCommand bool
+}
+
+func completeWords(c *cli.Context, words []string, index int) []candidate {
+	line := parseCompletionLine(c.App, words, index)
+
+	if line.command == nil || line.beforeCommand {
+		if line.pending != "" {
+			return nil
+		}
+		if strings.HasPrefix(line.current, "-") {
+			return filterCandidates(flagCandidates(c.App.Flags), line.current)
+		}
+		cands := []candidate{}
+		for _, cmd := range c.App.Commands {
+			if !cmd.Hidden {
+				cands = append(cands, candidate{Value: cmd.Name, Description: cmd.Description})
+			}
+		}
+		return filterCandidates(cands, line.current)
+	}
+
+	source := &completionSource{c: c, globals: line.globals}
+	domain := ""
+	if len(line.positional) > 0 {
+		domain = line.positional[0]
+	}
+
+	if line.pending != "" {
+		return completeFlagValue(source, line.pending, domain, "", line.current)
+	}
+	if strings.HasPrefix(line.current, "--") && strings.Contains(line.current, "=") {
+		kv := strings.SplitN(line.current, "=", 2)
+		return completeFlagValue(source, strings.TrimLeft(kv[0], "-"), domain, kv[0]+"=", kv[1])
+	}
+	if strings.HasPrefix(line.current, "-") {
+		return filterCandidates(flagCandidates(line.command.Flags), line.current)
+	}
+
+	if values, ok := completionArgs[line.command.Name]; ok {
+		cands := []candidate{}
+		for _, v := range values {
+			cands = append(cands, candidate{Value: v})
+		}
+		return filterCandidates(cands, line.current)
+	}
+	if len(line.positional) == 0 {
+		return filterCandidates(source.domains(), line.current)
+	}
+
+	return nil
+}
+
+// parseCompletionLine splits words into global flags, the command, its
+// flags and positional arguments, and the word at index being completed.
+func parseCompletionLine(app *cli.App, words []string, index int) completionLine {
+	line := completionLine{
+		globals: map[string]string{},
+		flags:   map[string]string{},
+		current: words[index],
+	}
+
+	flags := app.Flags
+	values := line.globals
+	pending := ""
+	for i, word := range words {
+		if i == index {
+			line.pending = pending
+			line.beforeCommand = line.command == nil
+			pending = ""
+			continue
+		}
+		if pending != "" {
+			values[pending] = word
+			pending = ""
+			continue
+		}
+		if strings.HasPrefix(word, "-") && word != "-" {
+			name := strings.TrimLeft(word, "-")
+			if kv := strings.SplitN(name, "=", 2); len(kv) == 2 {
+				values[canonicalFlag(flags, kv[0])] = kv[1]
+			} else if takesValue(flags, name) {
+				pending = canonicalFlag(flags, name)
+			}
+			continue
+		}
+		if line.command == nil {
+			line.command = app.Command(word)
+			if line.command == nil {
+				return line
+			}
+			flags = line.command.Flags
+			values = line.flags
+			continue
+		}
+		line.positional = append(line.positional, word)
+	}
+
+	return line
+}
+
+// flagNames returns a flag's name and aliases.
+func flagNames(flag cli.Flag) []string {
+	names := []string{}
+	for _, name := range strings.Split(flag.GetName(), ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+
+	return names
+}
+
+func findFlag(flags []cli.Flag, name string) cli.Flag {
+	for _, flag := range flags {
+		for _, n := range flagNames(flag) {
+			if n == name {
+				return flag
+			}
+		}
+	}
+
+	return nil
+}
+
+// canonicalFlag returns the primary name of a flag given any of its names.
+func canonicalFlag(flags []cli.Flag, name string) string {
+	if flag := findFlag(flags, name); flag != nil {
+		return flagNames(flag)[0]
+	}
+
+	return name
+}
+
+func takesValue(flags []cli.Flag, name string) bool {
+	switch flag := findFlag(flags, name).(type) {
+	case nil, cli.BoolFlag, cli.BoolTFlag:
+		return false
+	case cli.GenericFlag:
+		b, ok := flag.Value.(interface {
+			IsBoolFlag() bool
+		})
+		return !ok || !b.IsBoolFlag()
+	default:
+		return true
+	}
+}
+
+func flagCandidates(flags []cli.Flag) []candidate {
+	cands := []candidate{}
+	for _, flag := range flags {
+		usage := ""
+		if v := reflect.Indirect(reflect.ValueOf(flag)); v.Kind() == reflect.Struct {
+			if field := v.FieldByName("Usage"); field.Kind() == reflect.String {
+				usage = field.String()
+			}
+		}
+		for _, name := range flagNames(flag) {
+			prefix := "--"
+			if len(name) == 1 {
+				prefix = "-"
+			}
+			cands = append(cands, candidate{Value: prefix + name, Description: usage})
+		}
+	}
+
+	return cands
+}
+
+func completeFlagValue(source *completionSource, flag, domain, prefix, current string) []candidate {
+	if completionFileFlags[flag] {
+		return []candidate{{Value: completeFiles}}
+	}
+
+	var cands []candidate
+	switch flag {
+	case "domain":
+		cands = source.domains()
+	case "name":
+		cands = source.properties(domain)
+	case "names":
+		// complete the last of a comma-separated list
+		if i := strings.LastIndex(current, ","); i >= 0 {
+			prefix += current[:i+1]
+			current = current[i+1:]
+		}
+		cands = source.properties(domain)
+	case "id", "down", "closest", "map-dc":
+		cands = source.dataCenters(domain)
+	default:
+		for _, v := range completionValues[flag] {
+			cands = append(cands, candidate{Value: v})
+		}
+	}
+
+	cands = filterCandidates(cands, current)
+	for i := range cands {
+		cands[i].Value = prefix + cands[i].Value
+	}
+
+	return cands
+}
+
+func filterCandidates(cands []candidate, prefix string) []candidate {
+	filtered := []candidate{}
+	for _, cand := range cands {
+		if strings.HasPrefix(cand.Value, prefix) {
+			filtered = append(filtered, cand)
+		}
+	}
+
+	return filtered
+}
+
+// completionSource fetches candidates from the API through a cache.
+type completionSource struct {
+	c       *cli.Context
+	globals map[string]string
+}
+
+// global returns a global flag typed on the command line being completed,
+// falling back to its value from the environment.
+func (s *completionSource) global(name string) string {
+	if v, ok := s.globals[name]; ok {
+		return v
+	}
+
+	return s.c.GlobalString(name)
+}
+
+func (s *completionSource) domains() []candidate {
+	return s.cached("domains", func() ([]candidate, error) {
+		domains, err := s.api().Domains()
+		if err != nil {
+			return nil, err
+		}
+		cands := []candidate{}
+		for _, d := range domains {
+			cands = append(cands, candidate{Value: d.Name})
+		}
+		return cands, nil
+	})
+}
+
+func (s *completionSource) properties(domain string) []candidate {
+	if domain == "" {
+		return nil
+	}
+
+	return s.cached("properties-"+domain, func() ([]candidate, error) {
+		props, err := s.api().Properties(domain)
+		if err != nil {
+			return nil, err
+		}
+		cands := []candidate{}
+		for _, prop := range props.Properties {
+			cands = append(cands, candidate{Value: prop.Name, Description: prop.Type})
+		}
+		return cands, nil
+	})
+}
+
+func (s *completionSource) dataCenters(domain string) []candidate {
+	if domain == "" {
+		return nil
+	}
+
+	return s.cached("datacenters-"+domain, func() ([]candidate, error) {
+		dcs, err := s.api().DataCenters(domain)
+		if err != nil {
+			return nil, err
+		}
+		cands := []candidate{}
+		for _, dc := range dcs {
+			cands = append(cands, candidate{Value: strconv.Itoa(dc.DataCenterID), Description: dc.Nickname})
+		}
+		return cands, nil
+	})
+}
+
+func (s *completionSource) api() gtm.API {
+	return newAPI(s.global("access_token"), s.global("client_token"), s.global("client_secret"), s.global("host"))
+}
+
+// cached returns the candidates stored under key if they are younger than
+// completionTTL, and otherwise fetches and stores them. Cache entries are
+// kept per API host and client so accounts do not mix.
+func (s *completionSource) cached(key string, fetch func() ([]candidate, error)) []candidate {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(s.global("host") + "\x00" + s.global("client_token") + "\x00" + key))
+	path := filepath.Join(dir, "akamai-gtm", "completion", hex.EncodeToString(sum[:8])+".json")
+
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < completionTTL {
+		if data, err := ioutil.ReadFile(path); err == nil {
+			cands := []candidate{}
+			if json.Unmarshal(data, &cands) == nil {
+				return cands
+			}
+		}
+	}
+
+	cands, err := fetch()
+	if err != nil {
+		return nil
+	}
+	if data, err := json.Marshal(cands); err == nil {
+		if os.MkdirAll(filepath.Dir(path), 0700) == nil {
+			ioutil.WriteFile(path, data, 0600)
+		}
+	}
+
+	return cands
+}
+
+var completionScripts = map[string]string{
+	"bash": `# bash completion for {{.Name}}; load with: source <({{.Name}} completion bash)
+{{.Function}}() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local out
+    out=$({{.Name}} __complete "$((COMP_CWORD - 1))" "${COMP_WORDS[@]:1}" 2>/dev/null)
+    if [ "$out" = "` + completeFiles + `" ]; then
+        compopt -o filenames 2>/dev/null
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return
+    fi
+    local IFS=$'\n'
+    COMPREPLY=($(printf '%s\n' "$out" | cut -f1))
+}
+complete -F {{.Function}} {{.Name}}
+`,
+	"zsh": `#compdef {{.Name}}
+# zsh completion for {{.Name}}; load with: source <({{.Name}} completion zsh)
+{{.Function}}() {
+    local -a lines completions
+    local line value desc
+    lines=("${(@f)$({{.Name}} __complete "$((CURRENT - 2))" "${(@)words[2,-1]}" 2>/dev/null)}")
+    if [[ "${lines[1]}" == "` + completeFiles + `" ]]; then
+        _files
+        return
+    fi
+    for line in $lines; do
+        [[ -z "$line" ]] && continue
+        value="${line%%$'\t'*}"
+        desc=""
+        [[ "$line" == *$'\t'* ]] && desc="${line#*$'\t'}"
+        completions+=("${value//:/\\:}${desc:+:$desc}")
+    done
+    _describe -t values 'values' completions
+}
+compdef {{.Function}} {{.Name}}
+`,
+	"fish": `# fish completion for {{.Name}}; load with: {{.Name}} completion fish | source
+function __{{.Function}}_complete
+    set -l words (commandline -opc) (commandline -ct)
+    set -e words[1]
+    set -l out ({{.Name}} __complete (math (count $words) - 1) $words 2>/dev/null)
+    if test "$out[1]" = "` + completeFiles + `"
+        __fish_complete_path (commandline -ct)
+        return
+    end
+    printf '%s\n' $out
+end
+complete -c {{.Name}} -f -a '(__{{.Function}}_complete)'
+`,
+}
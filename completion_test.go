@@ -0,0 +1,161 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withCacheDir points the user cache directory at a temporary directory
+// and returns it along with a function restoring the environment.
+func withCacheDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "akamai-gtm-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, cache := os.Getenv("HOME"), os.Getenv("XDG_CACHE_HOME")
+	os.Setenv("HOME", dir)
+	os.Setenv("XDG_CACHE_HOME", dir)
+
+	return dir, func() {
+		os.Setenv("HOME", home)
+		os.Setenv("XDG_CACHE_HOME", cache)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestParseCompletionLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		words   []string
+		index   int
+		command string
+		want    completionLine
+	}{
+		{
+			name:  "command",
+			words: []string{"--host", "h.example.net", "prop"},
+			index: 2,
+			want: completionLine{
+				globals:       map[string]string{"host": "h.example.net"},
+				flags:         map[string]string{},
+				current:       "prop",
+				beforeCommand: true,
+			},
+		},
+		{
+			name:    "flag value",
+			words:   []string{"--at=token", "property", "--name", "w", testDomain},
+			index:   3,
+			command: "property",
+			want: completionLine{
+				globals:    map[string]string{"access_token": "token"},
+				flags:      map[string]string{},
+				positional: []string{testDomain},
+				pending:    "name",
+				current:    "w",
+			},
+		},
+		{
+			name:    "domain after flags",
+			words:   []string{"data-centers-delete", "--force", "--id", "3131", "ex"},
+			index:   4,
+			command: "data-centers-delete",
+			want: completionLine{
+				globals: map[string]string{},
+				flags:   map[string]string{"id": "3131"},
+				current: "ex",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := parseCompletionLine(newApp(), test.words, test.index)
+			command := ""
+			if got.command != nil {
+				command = got.command.Name
+			}
+			if command != test.command {
+				t.Errorf("command = %q, want %q", command, test.command)
+			}
+			got.command = nil
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("line = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompleteFromAPI(t *testing.T) {
+	_, restore := withCacheDir(t)
+	defer restore()
+	dir, err := ioutil.TempDir("", "akamai-gtm-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out, err := runCommand(t, newTestFake(), dir, "__complete", "2", "property", "--name", "w", testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "www") {
+		t.Errorf("candidates = %q, want property www", out)
+	}
+
+	out, err = runCommand(t, newTestFake(), dir, "__complete", "2", "data-center", "--id", "", testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"3131\teast", "3132\twest", "3133\tspare"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("candidates = %q, want %q", out, want)
+		}
+	}
+}
+
+func TestCompletionCacheExpiry(t *testing.T) {
+	dir, restore := withCacheDir(t)
+	defer restore()
+
+	source := &completionSource{globals: map[string]string{"host": "h.example.net", "client_token": "ct"}}
+	fetches := 0
+	fetch := func() ([]candidate, error) {
+		fetches++
+		return []candidate{{Value: "www"}}, nil
+	}
+
+	want := []candidate{{Value: "www"}}
+	for i, wantFetches := range []int{1, 1} {
+		if got := source.cached("properties", fetch); !reflect.DeepEqual(got, want) {
+			t.Errorf("call %d: candidates = %v, want %v", i, got, want)
+		}
+		if fetches != wantFetches {
+			t.Errorf("call %d: fetches = %d, want %d", i, fetches, wantFetches)
+		}
+	}
+
+	paths := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && filepath.Ext(path) == ".json" {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("cache files = %v, %v, want one", paths, err)
+	}
+	old := time.Now().Add(-completionTTL - time.Second)
+	if err := os.Chtimes(paths[0], old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	source.cached("properties", fetch)
+	if fetches != 2 {
+		t.Errorf("fetches after expiry = %d, want 2", fetches)
+	}
+}
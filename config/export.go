@@ -0,0 +1,209 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// export directory layout
+const (
+	ExportDomainFile     = "domain"
+	ExportDataCentersDir = "datacenters"
+	ExportPropertiesDir  = "properties"
+)
+
+// Export is the configuration of a domain, as exported to or loaded from
+// an export directory.
+type Export struct {
+	Domain      *edgegrid.Domain
+	DataCenters []edgegrid.DataCenter
+	Properties  []edgegrid.Property
+
+	// Files holds the file each object was loaded from, keyed by object ID
+	// (see ObjectID).
+	Files map[string]string
+}
+
+// FetchExport fetches the live configuration of a domain.
+func FetchExport(api gtm.API, domain string) (*Export, error) {
+	dom, err := api.Domain(domain)
+	if err != nil {
+		return nil, err
+	}
+	dcs, err := api.DataCenters(domain)
+	if err != nil {
+		return nil, err
+	}
+	props, err := api.Properties(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Export{
+		Domain:      dom,
+		DataCenters: dcs,
+		Properties:  props.Properties,
+		Files:       map[string]string{},
+	}, nil
+}
+
+// WriteExport writes a domain to dir as one file per object, calling
+// written with the path of each file:
+//
+//	domain.json
+//	datacenters/<dataCenterId>.json
+//	properties/<propertyName>.json
+//
+// The terraform format writes Terraform configuration instead (see
+// WriteTerraform).
+func WriteExport(exp *Export, dir, format string, written func(path string)) error {
+	var ext string
+	switch format {
+	case "", "json":
+		ext = ".json"
+	case "yaml":
+		ext = ".yaml"
+	case "terraform":
+		return WriteTerraform(exp, dir, written)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+
+	for _, sub := range []string{ExportDataCentersDir, ExportPropertiesDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return err
+		}
+	}
+
+	write := func(path string, v interface{}) error {
+		data, err := EncodeObject(v, ext)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+		if written != nil {
+			written(path)
+		}
+		return nil
+	}
+
+	if err := write(filepath.Join(dir, ExportDomainFile+ext), exp.Domain); err != nil {
+		return err
+	}
+	for _, dc := range exp.DataCenters {
+		path := filepath.Join(dir, ExportDataCentersDir, strconv.Itoa(dc.DataCenterID)+ext)
+		if err := write(path, dc); err != nil {
+			return err
+		}
+	}
+	for _, prop := range exp.Properties {
+		path := filepath.Join(dir, ExportPropertiesDir, prop.Name+ext)
+		if err := write(path, prop); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EncodeObject encodes v as indented JSON, or as YAML if ext is .yaml or
+// .yml.
+func EncodeObject(v interface{}, ext string) ([]byte, error) {
+	if ext == ".yaml" || ext == ".yml" {
+		return ToYAML(v)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// LoadExport reads a domain from an export directory.
+func LoadExport(dir string) (*Export, error) {
+	exp := &Export{Files: map[string]string{}}
+
+	for _, ext := range []string{".json", ".yaml", ".yml"} {
+		path := filepath.Join(dir, ExportDomainFile+ext)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		exp.Domain = &edgegrid.Domain{}
+		if err := UnmarshalFile(path, exp.Domain); err != nil {
+			return nil, err
+		}
+		exp.Files[ObjectID("domain", exp.Domain.Name)] = path
+		break
+	}
+	if exp.Domain == nil {
+		return nil, fmt.Errorf("%s is not an export directory: no %s.json or %s.yaml", dir, ExportDomainFile, ExportDomainFile)
+	}
+
+	dcFiles, err := exportFiles(filepath.Join(dir, ExportDataCentersDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range dcFiles {
+		dc := edgegrid.DataCenter{}
+		if err := UnmarshalFile(path, &dc); err != nil {
+			return nil, err
+		}
+		exp.DataCenters = append(exp.DataCenters, dc)
+		exp.Files[ObjectID("datacenter", strconv.Itoa(dc.DataCenterID))] = path
+	}
+
+	propFiles, err := exportFiles(filepath.Join(dir, ExportPropertiesDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range propFiles {
+		prop := edgegrid.Property{}
+		if err := UnmarshalFile(path, &prop); err != nil {
+			return nil, err
+		}
+		exp.Properties = append(exp.Properties, prop)
+		exp.Files[ObjectID("property", prop.Name)] = path
+	}
+
+	return exp, nil
+}
+
+// exportFiles lists the JSON and YAML files in dir, which need not exist.
+func exportFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, info := range infos {
+		switch strings.ToLower(filepath.Ext(info.Name())) {
+		case ".json", ".yaml", ".yml":
+			if !info.IsDir() {
+				files = append(files, filepath.Join(dir, info.Name()))
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// ObjectID identifies a GTM object as <type>:<name>, e.g. property:www.
+// Data centers are named by ID.
+func ObjectID(typ, name string) string {
+	return typ + ":" + name
+}
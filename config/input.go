@@ -0,0 +1,131 @@
+// Package config loads GTM object definitions: YAML and JSON input files,
+// input templates, and export directories.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Format is the encoding of an input file.
+type Format int
+
+// Input formats
+const (
+	FormatJSON Format = iota
+	FormatYAML
+)
+
+// ReadFile reads the file at path, or stdin when path is "-".
+func ReadFile(path string) ([]byte, error) {
+	switch path {
+	case "":
+		return nil, fmt.Errorf("--file is required")
+	case "-":
+		return ioutil.ReadAll(os.Stdin)
+	default:
+		return ioutil.ReadFile(path)
+	}
+}
+
+// DetectFormat determines the encoding of an input by its file extension,
+// falling back to inspecting the content for stdin and unknown extensions.
+func DetectFormat(path string, data []byte) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return FormatJSON
+	}
+
+	return FormatYAML
+}
+
+// YAMLToJSON converts a YAML object to JSON.
+func YAMLToJSON(data []byte) ([]byte, error) {
+	v := map[string]interface{}{}
+	if err := UnmarshalYAML(data, &v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalFile reads a YAML or JSON file into v. Unlike command inputs,
+// it is not rendered as a template.
+func UnmarshalFile(path string, v interface{}) error {
+	data, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if DetectFormat(path, data) == FormatYAML {
+		if data, err = YAMLToJSON(data); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	return nil
+}
+
+// UnmarshalYAML decodes YAML (and therefore JSON) into v, converting the
+// map[interface{}]interface{} values produced by the YAML decoder into
+// map[string]interface{} so the result is usable by templates and
+// encoding/json alike.
+func UnmarshalYAML(data []byte, v *map[string]interface{}) error {
+	raw := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*v = NormalizeYAML(raw).(map[string]interface{})
+
+	return nil
+}
+
+// NormalizeYAML converts the maps of a decoded YAML value to
+// map[string]interface{}.
+func NormalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range t {
+			m[fmt.Sprint(k)] = NormalizeYAML(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range t {
+			t[i] = NormalizeYAML(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// ToYAML encodes v as YAML using its JSON field names.
+func ToYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(doc)
+}
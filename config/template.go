@@ -0,0 +1,93 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs are the functions available to input templates.
+var TemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": func(sep string, v []interface{}) string {
+		s := []string{}
+		for _, i := range v {
+			s = append(s, fmt.Sprint(i))
+		}
+		return strings.Join(s, sep)
+	},
+}
+
+// RenderTemplate renders data as a Go text/template with vars. Referencing
+// a variable that has not been supplied is an error.
+func RenderTemplate(name string, data []byte, vars map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(TemplateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, vars); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// TemplateVars merges the variable files in order, followed by the
+// key=value pairs, with later values overriding earlier ones. Dotted keys
+// address nested values, e.g. east.weight=50.
+func TemplateVars(files []string, sets []string) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileVars := map[string]interface{}{}
+		if err := UnmarshalYAML(data, &fileVars); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		mergeVars(vars, fileVars)
+	}
+
+	for _, set := range sets {
+		kv := strings.SplitN(set, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid --set %q: expected key=value", set)
+		}
+		setVar(vars, strings.Split(kv[0], "."), kv[1])
+	}
+
+	return vars, nil
+}
+
+func mergeVars(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcOk := v.(map[string]interface{})
+		dstMap, dstOk := dst[k].(map[string]interface{})
+		if srcOk && dstOk {
+			mergeVars(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+func setVar(vars map[string]interface{}, path []string, value string) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := vars[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			vars[key] = next
+		}
+		vars = next
+	}
+	vars[path[len(path)-1]] = value
+}
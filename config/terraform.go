@@ -0,0 +1,481 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// terraform export files
+const (
+	TerraformFile       = "main.tf"
+	TerraformImportFile = "import.sh"
+)
+
+// WriteTerraform writes a domain to dir as Terraform configuration for the
+// Akamai provider's GTM resources, with a script importing the existing
+// objects into Terraform state, calling written with the path of each
+// file:
+//
+//	main.tf
+//	import.sh
+//
+// Data centers are referenced from traffic targets as resources rather than
+// by ID. Liveness test secrets are not written; they become sensitive
+// variables.
+func WriteTerraform(exp *Export, dir string, written func(path string)) error {
+	tf := newTerraform(exp)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data []byte
+		mode os.FileMode
+	}{
+		{TerraformFile, tf.config(), 0644},
+		{TerraformImportFile, tf.importScript(), 0755},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := ioutil.WriteFile(path, f.data, f.mode); err != nil {
+			return err
+		}
+		if written != nil {
+			written(path)
+		}
+	}
+
+	return nil
+}
+
+// terraform renders an export as HCL.
+type terraform struct {
+	exp *Export
+
+	// names are the resource names of the domain, data centers (by ID)
+	// and properties (by name).
+	domain      string
+	dataCenters map[int]string
+	properties  map[string]string
+	// used holds the names given so far, including variable names.
+	used map[string]bool
+
+	variables []*hclBlock
+}
+
+func newTerraform(exp *Export) *terraform {
+	tf := &terraform{
+		exp:         exp,
+		dataCenters: map[int]string{},
+		properties:  map[string]string{},
+		used:        map[string]bool{},
+	}
+
+	used := tf.used
+	tf.domain = terraformName(exp.Domain.Name, used)
+	for _, dc := range exp.DataCenters {
+		name := dc.Nickname
+		if name == "" {
+			name = "datacenter_" + strconv.Itoa(dc.DataCenterID)
+		}
+		tf.dataCenters[dc.DataCenterID] = terraformName(name, used)
+	}
+	for _, prop := range exp.Properties {
+		tf.properties[prop.Name] = terraformName(prop.Name, used)
+	}
+
+	return tf
+}
+
+// config returns the HCL of the domain and its objects.
+func (tf *terraform) config() []byte {
+	blocks := []*hclBlock{tf.domainBlock()}
+	for _, dc := range tf.exp.DataCenters {
+		blocks = append(blocks, tf.dataCenterBlock(dc))
+	}
+	for _, prop := range tf.exp.Properties {
+		blocks = append(blocks, tf.propertyBlock(prop))
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# Generated by akamai-gtm export from %s.\n", tf.exp.Domain.Name)
+	header := &hclBlock{}
+	header.add("required_providers", "", &hclBlock{attrs: []hclAttr{{"akamai", hclExpr(`{ source = "akamai/akamai" }`)}}})
+	(&hclBlock{}).add("terraform", "", header).write(buf, 0)
+	for _, name := range []string{"contract", "group"} {
+		v := &hclBlock{}
+		v.set("type", hclExpr("string"))
+		v.set("description", fmt.Sprintf("The %s ID of %s", name, tf.exp.Domain.Name))
+		(&hclBlock{}).add("variable", strconv.Quote(name), v).write(buf, 0)
+	}
+	for _, v := range tf.variables {
+		v.write(buf, 0)
+	}
+	for _, b := range blocks {
+		b.write(buf, 0)
+	}
+
+	return buf.Bytes()
+}
+
+func (tf *terraform) domainBlock() *hclBlock {
+	d := tf.exp.Domain
+	b := &hclBlock{}
+	b.set("name", d.Name)
+	b.set("contract", hclExpr("var.contract"))
+	b.set("group", hclExpr("var.group"))
+	b.set("type", d.Type)
+	b.setNonZero("comment", d.ModificationComments)
+
+	return resource("akamai_gtm_domain", tf.domain, b)
+}
+
+func (tf *terraform) dataCenterBlock(dc edgegrid.DataCenter) *hclBlock {
+	b := &hclBlock{}
+	b.set("domain", tf.domainRef())
+	b.set("nickname", dc.Nickname)
+	b.setNonZero("city", dc.City)
+	b.setNonZero("state_or_province", dc.StateOrProvince)
+	b.setNonZero("country", dc.Country)
+	b.setNonZero("continent", dc.Continent)
+	b.setNonZero("latitude", dc.Latitude)
+	b.setNonZero("longitude", dc.Longitude)
+	b.setNonZero("cloud_server_targeting", dc.CloudServerTargeting)
+	b.setNonZero("clone_of", dc.CloneOf)
+	b.set("wait_on_complete", false)
+
+	return resource("akamai_gtm_datacenter", tf.dataCenters[dc.DataCenterID], b)
+}
+
+func (tf *terraform) propertyBlock(prop edgegrid.Property) *hclBlock {
+	b := &hclBlock{}
+	b.set("domain", tf.domainRef())
+	b.set("name", prop.Name)
+	b.set("type", prop.Type)
+	b.setNonZero("score_aggregation_type", prop.ScoreAggregationType)
+	b.setNonZero("handout_mode", prop.HandoutMode)
+	b.set("failover_delay", prop.FailoverDelay)
+	b.set("failback_delay", prop.FailbackDelay)
+	b.setNonZero("dynamic_ttl", prop.DynamicTTL)
+	b.setNonZero("static_ttl", prop.StaticTTL)
+	b.setNonZero("ipv6", prop.Ipv6)
+	b.setNonZero("cname", prop.Cname)
+	b.setNonZero("backup_cname", prop.BackupCname)
+	b.setNonZero("backup_ip", prop.BackupIP)
+	b.setNonZero("balance_by_download_score", prop.BalanceByDownloadScore)
+	b.setNonZero("health_max", prop.HealthMax)
+	b.setNonZero("health_multiplier", prop.HealthMultiplier)
+	b.setNonZero("health_threshold", prop.HealthThreshold)
+	b.setNonZero("load_imbalance_percentage", prop.LoadImbalancePercentage)
+	b.setNonZero("map_name", prop.MapName)
+	b.setNonZero("max_unreachable_penalty", prop.MaxUnreachablePenalty)
+	b.setNonZero("stickiness_bonus_constant", prop.StickinessBonusConstant)
+	b.setNonZero("stickiness_bonus_percentage", prop.StickinessBonusPercentage)
+	b.setNonZero("unreachable_threshold", prop.UnreachableThreshold)
+	b.setNonZero("use_computed_targets", prop.UseComputedTargets)
+	b.setNonZero("comments", prop.Comments)
+	b.set("wait_on_complete", false)
+
+	for _, target := range prop.TrafficTargets {
+		t := &hclBlock{}
+		t.set("datacenter_id", tf.dataCenterRef(target.DataCenterID))
+		t.set("enabled", target.Enabled)
+		t.set("weight", target.Weight)
+		t.setNonZero("name", target.Name)
+		t.setNonZero("handout_cname", target.HandoutCname)
+		servers := target.Servers
+		if servers == nil {
+			servers = []string{}
+		}
+		t.set("servers", servers)
+		b.add("traffic_target", "", t)
+	}
+
+	for _, test := range prop.LivenessTests {
+		t := &hclBlock{}
+		t.set("name", test.Name)
+		t.set("test_object_protocol", test.TestObjectProtocol)
+		t.set("test_interval", test.TestInterval)
+		t.set("test_timeout", test.TestTimeout)
+		t.setNonZero("test_object", test.TestObject)
+		t.setNonZero("test_object_port", test.TestObjectPort)
+		t.setNonZero("test_object_username", test.TestObjectUsername)
+		t.setNonZero("host_header", test.HostHeader)
+		t.setNonZero("request_string", test.RequestString)
+		t.setNonZero("response_string", test.ResponseString)
+		t.setNonZero("http_error3xx", test.HTTPError3xx)
+		t.setNonZero("http_error4xx", test.HTTPError4xx)
+		t.setNonZero("http_error5xx", test.HTTPError5xx)
+		t.setNonZero("disable_nonstandard_port_warning", test.DisableNonstandardPortWarning)
+		secrets := []struct {
+			attr, value string
+		}{
+			{"test_object_password", test.TestObjectPassword},
+			{"ssl_client_private_key", test.SSLClientPrivateKey},
+			{"ssl_client_certificate", test.SSLCertificate},
+		}
+		for _, s := range secrets {
+			if s.value != "" {
+				t.set(s.attr, tf.secret(prop.Name, test.Name, s.attr))
+			}
+		}
+		b.add("liveness_test", "", t)
+	}
+
+	return resource("akamai_gtm_property", tf.properties[prop.Name], b)
+}
+
+// secret declares a sensitive variable for a liveness test secret and
+// returns a reference to it.
+func (tf *terraform) secret(prop, test, attr string) hclExpr {
+	name := terraformName(prop+"_"+test+"_"+attr, tf.used)
+	v := &hclBlock{}
+	v.set("type", hclExpr("string"))
+	v.set("description", fmt.Sprintf("The %s of liveness test %s of property %s", strings.Replace(attr, "_", " ", -1), test, prop))
+	v.set("sensitive", true)
+	tf.variables = append(tf.variables, (&hclBlock{}).add("variable", strconv.Quote(name), v))
+
+	return hclExpr("var." + name)
+}
+
+func (tf *terraform) domainRef() hclExpr {
+	return hclExpr("akamai_gtm_domain." + tf.domain + ".name")
+}
+
+// dataCenterRef references a data center resource, or is the bare ID of a
+// data center that is not part of the export, such as Akamai's default
+// data centers.
+func (tf *terraform) dataCenterRef(id int) interface{} {
+	if name, ok := tf.dataCenters[id]; ok {
+		return hclExpr("akamai_gtm_datacenter." + name + ".datacenter_id")
+	}
+
+	return id
+}
+
+// importScript returns a shell script importing the domain's existing
+// objects into Terraform state.
+func (tf *terraform) importScript() []byte {
+	domain := tf.exp.Domain.Name
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "#!/bin/sh\n# Imports the existing objects of %s into Terraform state.\n", domain)
+	fmt.Fprintf(buf, "# Set TF_VAR_contract and TF_VAR_group first.\nset -e\n\n")
+
+	imp := func(addr, id string) {
+		fmt.Fprintf(buf, "terraform import %s %s\n", addr, shellQuote(id))
+	}
+	imp("akamai_gtm_domain."+tf.domain, domain)
+	for _, dc := range tf.exp.DataCenters {
+		imp("akamai_gtm_datacenter."+tf.dataCenters[dc.DataCenterID], domain+":"+strconv.Itoa(dc.DataCenterID))
+	}
+	for _, prop := range tf.exp.Properties {
+		imp("akamai_gtm_property."+tf.properties[prop.Name], domain+":"+prop.Name)
+	}
+
+	return buf.Bytes()
+}
+
+// terraformName turns name into a Terraform identifier that is not yet in
+// used, and marks it used. Other characters become underscores.
+func terraformName(name string, used map[string]bool) string {
+	b := []rune{}
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			b = append(b, r)
+		case len(b) == 0 || b[len(b)-1] != '_':
+			b = append(b, '_')
+		}
+	}
+	base := strings.Trim(string(b), "_")
+	if base == "" || (base[0] >= '0' && base[0] <= '9') || base[0] == '-' {
+		base = "_" + base
+	}
+
+	id := base
+	for i := 2; used[id]; i++ {
+		id = base + "_" + strconv.Itoa(i)
+	}
+	used[id] = true
+
+	return id
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// hclExpr is an HCL expression written as is, such as a reference.
+type hclExpr string
+
+type hclAttr struct {
+	name  string
+	value interface{}
+}
+
+// hclBlock is the body of an HCL block: attributes followed by nested
+// blocks, written in the order they were added.
+type hclBlock struct {
+	attrs  []hclAttr
+	blocks []hclNested
+}
+
+type hclNested struct {
+	typ, label string
+	body       *hclBlock
+}
+
+func resource(typ, name string, body *hclBlock) *hclBlock {
+	return (&hclBlock{}).add("resource", strconv.Quote(typ)+" "+strconv.Quote(name), body)
+}
+
+func (b *hclBlock) set(name string, value interface{}) {
+	b.attrs = append(b.attrs, hclAttr{name, value})
+}
+
+// setNonZero sets an attribute unless value is its type's zero value, so
+// that unset settings are left to the provider's defaults.
+func (b *hclBlock) setNonZero(name string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case string:
+		if v == "" {
+			return
+		}
+	case bool:
+		if !v {
+			return
+		}
+	case int:
+		if v == 0 {
+			return
+		}
+	case int64:
+		if v == 0 {
+			return
+		}
+	case float64:
+		if v == 0 {
+			return
+		}
+	}
+	b.set(name, value)
+}
+
+func (b *hclBlock) add(typ, label string, body *hclBlock) *hclBlock {
+	b.blocks = append(b.blocks, hclNested{typ, label, body})
+	return b
+}
+
+// write writes the block's contents at the given indent, aligning the
+// equals signs of the attributes as terraform fmt does. Top-level blocks
+// are separated by blank lines.
+func (b *hclBlock) write(buf *bytes.Buffer, indent int) {
+	pad := strings.Repeat("  ", indent)
+	width := 0
+	for _, a := range b.attrs {
+		if len(a.name) > width {
+			width = len(a.name)
+		}
+	}
+	for _, a := range b.attrs {
+		fmt.Fprintf(buf, "%s%-*s = %s\n", pad, width, a.name, hclValue(a.value))
+	}
+	for i, nested := range b.blocks {
+		if indent == 0 || i > 0 || len(b.attrs) > 0 {
+			buf.WriteString("\n")
+		}
+		header := nested.typ
+		if nested.label != "" {
+			header += " " + nested.label
+		}
+		fmt.Fprintf(buf, "%s%s {\n", pad, header)
+		nested.body.write(buf, indent+1)
+		fmt.Fprintf(buf, "%s}\n", pad)
+	}
+}
+
+// hclValue formats a value as an HCL literal.
+func hclValue(value interface{}) string {
+	switch v := value.(type) {
+	case hclExpr:
+		return string(v)
+	case string:
+		return hclString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		items := []string{}
+		for _, s := range v {
+			items = append(items, hclString(s))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []interface{}:
+		items := []string{}
+		for _, i := range v {
+			items = append(items, hclValue(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := []string{}
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := []string{}
+		for _, k := range keys {
+			items = append(items, hclString(k)+" = "+hclValue(v[k]))
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	case nil:
+		return "null"
+	}
+
+	return hclString(fmt.Sprint(value))
+}
+
+// hclString quotes s, escaping template sequences so that they are taken
+// literally.
+func hclString(s string) string {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('"')
+	for i, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r < ' ':
+			fmt.Fprintf(buf, `\u%04x`, r)
+		case (r == '$' || r == '%') && strings.HasPrefix(s[i+1:], "{"):
+			buf.WriteRune(r)
+			buf.WriteRune(r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+
+	return buf.String()
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// conflictFlags are the flags of the commands that check for concurrent
+// changes before updating an object.
+var conflictFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "merge",
+		Usage: "Merge with changes made to the live object since, without asking",
+	},
+	cli.BoolFlag{
+		Name:  "force",
+		Usage: "Overwrite changes made to the live object since",
+	},
+}
+
+// objectVersion returns the version of an object: the LastModified time of
+// a property or the ChangeID of a domain. Data centers have none.
+func objectVersion(v interface{}) string {
+	switch obj := v.(type) {
+	case *edgegrid.Property:
+		return obj.LastModified
+	case *edgegrid.Domain:
+		if obj.Status != nil {
+			return obj.Status.ChangeID
+		}
+	}
+
+	return ""
+}
+
+// update is an update to an object based on an earlier version of it.
+type update struct {
+	objectType, name string
+	// base is the version the update is based on, nil if it is not known,
+	// and version is its objectVersion, if any.
+	base    interface{}
+	version string
+	mine    interface{}
+	live    interface{}
+	// empty returns an empty object to decode a merged update into.
+	empty func() interface{}
+}
+
+// fileUpdate checks an update read from a file against the live object,
+// using the version recorded in the file, for example by export. It
+// returns the object to submit; see resolveConflict.
+func fileUpdate(c *cli.Context, domain, objectType, name string, mine interface{}, fetch func() (interface{}, error), empty func() interface{}) (interface{}, error) {
+	version := objectVersion(mine)
+	if version == "" || c.Bool("force") {
+		return mine, nil
+	}
+	live, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	u := &update{objectType: objectType, name: name, version: version, mine: mine, live: live, empty: empty}
+	base := empty()
+	found, err := loadBase(c, domain, objectType, name, version, base)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		u.base = base
+	}
+
+	return resolveConflict(c, u)
+}
+
+// resolveConflict checks that the live object has not changed since the
+// version an update is based on. If it has, it shows what changed on each
+// side and, when the changes do not overlap and the user agrees, merges
+// them. It returns the object to submit.
+func resolveConflict(c *cli.Context, u *update) (interface{}, error) {
+	if c.Bool("force") {
+		return u.mine, nil
+	}
+	liveVersion := objectVersion(u.live)
+	switch {
+	case u.version != "":
+		if liveVersion == u.version {
+			return u.mine, nil
+		}
+	case u.base != nil:
+		if same, err := sameDocument(u.base, u.live); err != nil || same {
+			return u.mine, err
+		}
+	default:
+		return u.mine, nil
+	}
+
+	id := config.ObjectID(u.objectType, u.name)
+	if u.version != "" {
+		fmt.Printf("%s has changed since version %s the update is based on; it is now at %s\n", id, u.version, liveVersion)
+	} else {
+		fmt.Printf("%s has changed since the update was started\n", id)
+	}
+	if u.base == nil {
+		diff, err := jsonDiff("live", "yours", u.live, u.mine)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Print(diff)
+		return nil, fmt.Errorf("%s not updated: version %s is not known here, so the changes cannot be merged; update it from the live version, or use --force to overwrite it", id, u.version)
+	}
+
+	theirs, err := jsonDiff("base", "live", u.base, u.live)
+	if err != nil {
+		return nil, err
+	}
+	yours, err := jsonDiff("base", "yours", u.base, u.mine)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Changes made since:\n%s\nYour changes:\n%s\n", theirs, yours)
+
+	docs := make([]interface{}, 3)
+	for i, v := range []interface{}{u.base, u.live, u.mine} {
+		if docs[i], err = toDocument(v); err != nil {
+			return nil, err
+		}
+	}
+	merged, conflicts := mergeDocuments(docs[0], docs[1], docs[2], "")
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%s not updated: both changed %s; update it from the live version, or use --force to overwrite it", id, strings.Join(conflicts, ", "))
+	}
+
+	if !c.Bool("merge") {
+		answer, err := prompt("[m]erge your changes into the live version or [a]bort? ", "--merge or --force")
+		if err != nil {
+			return nil, err
+		}
+		if answer = strings.ToLower(answer); answer != "m" && answer != "merge" {
+			return nil, fmt.Errorf("%s not updated", id)
+		}
+	}
+
+	result := u.empty()
+	if err := fromDocument(merged, result); err != nil {
+		return nil, err
+	}
+	diff, err := jsonDiff("live", "merged", u.live, result)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Merged update:\n%s", diff)
+
+	return result, nil
+}
+
+// mergeDocuments merges the changes from base to live and from base to
+// mine, field by field. Arrays are merged as a whole. It returns the paths
+// changed differently on both sides.
+func mergeDocuments(base, live, mine interface{}, path string) (interface{}, []string) {
+	switch {
+	case reflect.DeepEqual(mine, base), reflect.DeepEqual(live, mine):
+		return live, nil
+	case reflect.DeepEqual(live, base):
+		return mine, nil
+	}
+
+	baseObj, ok1 := base.(map[string]interface{})
+	liveObj, ok2 := live.(map[string]interface{})
+	mineObj, ok3 := mine.(map[string]interface{})
+	if !ok1 || !ok2 || !ok3 {
+		if path == "" {
+			path = "/"
+		}
+		return mine, []string{path}
+	}
+
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, obj := range []map[string]interface{}{baseObj, liveObj, mineObj} {
+		for k := range obj {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	merged := map[string]interface{}{}
+	conflicts := []string{}
+	for _, k := range keys {
+		v, c := mergeDocuments(baseObj[k], liveObj[k], mineObj[k], path+"/"+k)
+		if v != nil {
+			merged[k] = v
+		}
+		conflicts = append(conflicts, c...)
+	}
+
+	return merged, conflicts
+}
+
+// sameDocument reports whether a and b have the same JSON encoding.
+func sameDocument(a, b interface{}) (bool, error) {
+	aDoc, err := toDocument(a)
+	if err != nil {
+		return false, err
+	}
+	bDoc, err := toDocument(b)
+	if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(aDoc, bDoc), nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/urfave/cli"
+)
+
+// domainDeletePollInterval is how often a deleted domain is checked for
+// while waiting for the deletion to be confirmed.
+const domainDeletePollInterval = 5 * time.Second
+
+// domainContents are the kinds of objects in a domain in the order a
+// cascading delete removes them: properties first, as they refer to data
+// centers and maps, and data centers last, as maps and resources refer to
+// them. collection is the collection for gtm.API's ObjectDelete, or "" for
+// the objects with their own delete calls.
+var domainContents = []struct {
+	key, objectType, collection string
+}{
+	{"properties", "property", ""},
+	{"geographicMaps", "geographic-map", gtm.GeographicMaps},
+	{"cidrMaps", "cidr-map", gtm.CIDRMaps},
+	{"asMaps", "as-map", gtm.ASMaps},
+	{"resources", "resource", gtm.Resources},
+	{"datacenters", "datacenter", ""},
+}
+
+func domainDelete(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return fmt.Errorf("a Domain name is required")
+	}
+	api := client(c)
+	doc, err := api.DomainDocument(name)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	total := 0
+	for _, kind := range domainContents {
+		names := contentNames(doc, kind.key)
+		count := len(names)
+		total += count
+		if count > 10 {
+			names = append(names[:10], fmt.Sprintf("and %d more", count-10))
+		}
+		rows = append(rows, []string{kind.key, strconv.Itoa(count), strings.Join(names, ", ")})
+	}
+	fmt.Printf("Domain %s contains:\n", name)
+	render.TableWithHeaders(os.Stdout, []string{"Objects", "Count", "Names"}, rows)
+
+	if total > 0 && !c.Bool("cascade") {
+		return fmt.Errorf("%s is not empty: delete its %d objects first, or use --cascade to delete them with it", name, total)
+	}
+
+	if confirmed := c.String("confirm"); confirmed != "" {
+		if confirmed != name {
+			return fmt.Errorf("--confirm %s does not match the Domain name %s", confirmed, name)
+		}
+	} else {
+		answer, err := prompt(fmt.Sprintf("This deletes %s and its %d objects. Type the Domain name to confirm: ", name, total), "--confirm <domain>")
+		if err != nil {
+			return err
+		}
+		if answer != name {
+			return fmt.Errorf("not deleted: %q does not match the Domain name", answer)
+		}
+	}
+
+	backup := c.String("backup")
+	if backup == "" {
+		backup = fmt.Sprintf("%s-%s.json", name, time.Now().UTC().Format("20060102T150405Z"))
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(backup, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("not deleted: could not save backup: %v", err)
+	}
+	fmt.Printf("Saved backup to %s\n", backup)
+
+	if err := deleteDomainContents(api, name, doc); err != nil {
+		return fmt.Errorf("%v; the backup is in %s", err, backup)
+	}
+
+	if _, err := api.DomainDelete(name); err != nil {
+		return fmt.Errorf("%v; the backup is in %s", err, backup)
+	}
+
+	wait := c.Duration("wait")
+	if wait == 0 {
+		fmt.Printf("Submitted deletion of %s\n", name)
+		return nil
+	}
+	fmt.Printf("Submitted deletion of %s; waiting for it to be confirmed\n", name)
+	deadline := time.Now().Add(wait)
+	for {
+		_, err := api.DomainDocument(name)
+		if gtm.IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("waiting for the deletion of %s to be confirmed: %v", name, err)
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%s still exists after %s", name, wait)
+		}
+		time.Sleep(domainDeletePollInterval)
+	}
+	fmt.Printf("Deleted %s\n", name)
+
+	return nil
+}
+
+// deleteDomainContents deletes the objects of a domain in dependency
+// order, stopping at the first failure.
+func deleteDomainContents(api gtm.API, domain string, doc map[string]interface{}) error {
+	for _, kind := range domainContents {
+		items, _ := doc[kind.key].([]interface{})
+		for _, item := range items {
+			obj, _ := item.(map[string]interface{})
+			objName, _ := obj["name"].(string)
+			var err error
+			switch kind.key {
+			case "properties":
+				var deleted bool
+				if deleted, err = api.PropertyDelete(domain, objName); err == nil && !deleted {
+					err = fmt.Errorf("the API did not delete it")
+				}
+			case "datacenters":
+				id, _ := obj["datacenterId"].(float64)
+				objName = fmt.Sprintf("%s (%d)", obj["nickname"], int(id))
+				err = api.DataCenterDelete(domain, int(id))
+			default:
+				_, err = api.ObjectDelete(domain, kind.collection, objName)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to delete %s %s: %v", kind.objectType, objName, err)
+			}
+			fmt.Printf("Deleted %s %s\n", kind.objectType, objName)
+		}
+	}
+
+	return nil
+}
+
+// contentNames returns the names of the objects of a domain under key, or
+// the nicknames of its data centers, sorted.
+func contentNames(doc map[string]interface{}, key string) []string {
+	items, _ := doc[key].([]interface{})
+	names := []string{}
+	for _, item := range items {
+		obj, _ := item.(map[string]interface{})
+		if key == "datacenters" {
+			names = append(names, fmt.Sprint(obj["nickname"]))
+			continue
+		}
+		names = append(names, fmt.Sprint(obj["name"]))
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// editErrorPrefix starts the lines added to the top of a reopened file to
+// explain why the last edit was not accepted. They are removed before the
+// file is decoded.
+const editErrorPrefix = "# akamai-gtm: "
+
+// editFlags are the flags of each edit subcommand.
+var editFlags = append([]cli.Flag{
+	cli.StringFlag{
+		Name:  "format",
+		Value: "yaml",
+		Usage: "The format to edit in: yaml or json",
+	},
+	cli.BoolFlag{
+		Name:  "yes",
+		Usage: "Submit the changes without asking for confirmation",
+	},
+}, conflictFlags...)
+
+// editTarget is an object the edit command changes.
+type editTarget struct {
+	// kind and name identify the object, e.g. property www, and
+	// objectType is its type as in config.ObjectID.
+	kind, name, objectType string
+	live                   interface{}
+	// fetch returns the live object again, to check that it has not
+	// changed since it was opened.
+	fetch func() (interface{}, error)
+	// edited returns an empty object to decode the edited file into.
+	edited func() interface{}
+	// validate checks an edited object before it is submitted.
+	validate func(edited interface{}) error
+	// submit updates the object and returns what to report.
+	submit func(edited interface{}) (string, error)
+}
+
+// edit opens target in the user's editor until the edited object is valid
+// and then, once the diff is confirmed, submits it. Errors, including the
+// API rejecting the update, reopen the editor with the error at the top.
+func edit(c *cli.Context, target *editTarget) error {
+	ext := ".yaml"
+	switch c.String("format") {
+	case "", "yaml":
+	case "json":
+		ext = ".json"
+	default:
+		return fmt.Errorf("unknown format %q: use yaml or json", c.String("format"))
+	}
+	data, err := config.EncodeObject(target.live, ext)
+	if err != nil {
+		return err
+	}
+	name := target.kind + "-" + target.name + ext
+
+	var problem error
+	for {
+		opened := data
+		if problem != nil {
+			opened = withEditError(data, problem)
+		}
+		edited, err := editFile(name, opened)
+		if err != nil {
+			return err
+		}
+		edited = withoutEditErrors(edited)
+		if len(bytes.TrimSpace(edited)) == 0 {
+			fmt.Println("The file is empty; nothing was updated")
+			return nil
+		}
+		if problem != nil && bytes.Equal(edited, data) {
+			// saved without trying to fix the problem
+			return problem
+		}
+		data = edited
+
+		updated := target.edited()
+		if problem = decodeEdited(name, data, updated); problem == nil {
+			problem = target.validate(updated)
+		}
+		if problem != nil {
+			continue
+		}
+
+		diff, err := jsonDiff("live", "edited", target.live, updated)
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			fmt.Println("No changes")
+			return nil
+		}
+		fmt.Print(diff)
+
+		if !c.Bool("yes") {
+			answer, err := prompt(fmt.Sprintf("Update %s %s? [y]es, [e]dit again or [n]o: ", target.kind, target.name), "--yes")
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(answer) {
+			case "y", "yes":
+			case "e", "edit":
+				continue
+			default:
+				return fmt.Errorf("%s %s not updated", target.kind, target.name)
+			}
+		}
+
+		live, err := target.fetch()
+		if err != nil {
+			return err
+		}
+		submit, err := resolveConflict(c, &update{
+			objectType: target.objectType,
+			name:       target.name,
+			base:       target.live,
+			version:    objectVersion(target.live),
+			mine:       updated,
+			live:       live,
+			empty:      target.edited,
+		})
+		if err != nil {
+			return err
+		}
+		result, err := target.submit(submit)
+		if err != nil {
+			fmt.Printf("The update was rejected: %v\n", err)
+			problem = err
+			continue
+		}
+		fmt.Println(result)
+
+		return nil
+	}
+}
+
+func editProperty(c *cli.Context) error {
+	domain := c.Args().First()
+	name := c.String("name")
+	if name == "" {
+		return fmt.Errorf("--name is required")
+	}
+	api := client(c)
+	live, err := api.Property(domain, name)
+	if err != nil {
+		return err
+	}
+
+	return edit(c, &editTarget{
+		kind:       "property",
+		name:       name,
+		objectType: "property",
+		live:       live,
+		fetch: func() (interface{}, error) {
+			return api.Property(domain, name)
+		},
+		edited: func() interface{} { return &edgegrid.Property{} },
+		validate: func(edited interface{}) error {
+			if prop := edited.(*edgegrid.Property); prop.Name != name {
+				return fmt.Errorf("the property name cannot be changed from %s to %s", name, prop.Name)
+			}
+			return nil
+		},
+		submit: func(edited interface{}) (string, error) {
+			resp, err := api.PropertyUpdate(domain, edited.(*edgegrid.Property))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Updated property %s", resp.Property.Name), nil
+		},
+	})
+}
+
+func editDataCenter(c *cli.Context) error {
+	domain := c.Args().First()
+	id := c.Int("id")
+	if id == 0 {
+		return fmt.Errorf("--id is required")
+	}
+	api := client(c)
+	live, err := api.DataCenter(domain, id)
+	if err != nil {
+		return err
+	}
+
+	return edit(c, &editTarget{
+		kind:       "data-center",
+		name:       strconv.Itoa(id),
+		objectType: "datacenter",
+		live:       live,
+		fetch: func() (interface{}, error) {
+			return api.DataCenter(domain, id)
+		},
+		edited: func() interface{} { return &edgegrid.DataCenter{} },
+		validate: func(edited interface{}) error {
+			if dc := edited.(*edgegrid.DataCenter); dc.DataCenterID != id {
+				return fmt.Errorf("the data center ID cannot be changed from %d to %d", id, dc.DataCenterID)
+			}
+			return nil
+		},
+		submit: func(edited interface{}) (string, error) {
+			resp, err := api.DataCenterUpdate(domain, edited.(*edgegrid.DataCenter))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Updated %s", resp.DataCenter.Nickname), nil
+		},
+	})
+}
+
+func editDomain(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return fmt.Errorf("a Domain name is required")
+	}
+	api := client(c)
+	live, err := api.Domain(name)
+	if err != nil {
+		return err
+	}
+
+	return edit(c, &editTarget{
+		kind:       "domain",
+		name:       name,
+		objectType: "domain",
+		live:       live,
+		fetch: func() (interface{}, error) {
+			return api.Domain(name)
+		},
+		edited: func() interface{} { return &edgegrid.Domain{} },
+		validate: func(edited interface{}) error {
+			if domain := edited.(*edgegrid.Domain); domain.Name != name {
+				return fmt.Errorf("the domain name cannot be changed from %s to %s", name, domain.Name)
+			}
+			return nil
+		},
+		submit: func(edited interface{}) (string, error) {
+			resp, err := api.DomainUpdate(edited.(*edgegrid.Domain))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Updated domain: %s", resp.Domain.Name), nil
+		},
+	})
+}
+
+// withEditError adds err to the top of data as comment lines.
+func withEditError(data []byte, err error) []byte {
+	out := &bytes.Buffer{}
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintf(out, "%s%s\n", editErrorPrefix, line)
+	}
+	fmt.Fprintf(out, "%sfix the problem above and save, or empty the file to cancel\n", editErrorPrefix)
+	out.Write(data)
+
+	return out.Bytes()
+}
+
+// withoutEditErrors removes the lines withEditError added.
+func withoutEditErrors(data []byte) []byte {
+	for bytes.HasPrefix(data, []byte(editErrorPrefix)) {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return nil
+		}
+		data = data[i+1:]
+	}
+
+	return data
+}
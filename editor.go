@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/config"
+)
+
+// editorCommand returns the user's editor from $VISUAL or $EDITOR, which
+// may include arguments, e.g. "code --wait".
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+
+	return []string{"vi"}
+}
+
+// runEditor opens path in the user's editor and waits for it to exit.
+func runEditor(path string) error {
+	args := append(editorCommand(), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// editObject writes v to a temporary file in the given format (yaml or
+// json), opens it in the user's editor and decodes the result into edited.
+// It returns the edited file's contents so a failed edit can be reopened.
+func editObject(name, format string, v, edited interface{}) ([]byte, error) {
+	ext := ".yaml"
+	if format == "json" {
+		ext = ".json"
+	}
+	data, err := config.EncodeObject(v, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	return editData(name+ext, data, edited)
+}
+
+// editData opens data in the user's editor, in a temporary file named
+// after name, and decodes the result into edited.
+func editData(name string, data []byte, edited interface{}) ([]byte, error) {
+	data, err := editFile(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, decodeEdited(name, data, edited)
+}
+
+// decodeEdited decodes an edited file named name, in YAML or JSON, into
+// edited. Fields edited does not have are an error, to catch typos.
+func decodeEdited(name string, data []byte, edited interface{}) error {
+	var err error
+	if config.DetectFormat(name, data) == config.FormatYAML {
+		if data, err = config.YAMLToJSON(data); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(edited); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	return nil
+}
+
+// editFile opens data in the user's editor, in a temporary file named
+// after name, and returns the edited contents.
+func editFile(name string, data []byte) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "akamai-gtm-edit")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, strings.Replace(name, string(filepath.Separator), "_", -1))
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	if err := runEditor(path); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(path)
+}
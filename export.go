@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/urfave/cli"
+)
+
+func export(c *cli.Context) error {
+	domain := c.Args().First()
+	exp, err := config.FetchExport(client(c), domain)
+	if err != nil {
+		return err
+	}
+
+	dir := c.String("dir")
+	if dir == "" {
+		dir = domain
+	}
+
+	err = config.WriteExport(exp, dir, c.String("format"), func(path string) {
+		fmt.Printf("Wrote %s\n", path)
+	})
+	if err != nil {
+		return err
+	}
+
+	// keep the exported versions, so that updates made from the files can
+	// be merged with changes made since
+	err = saveBase(c, domain, "domain", domain, exp.Domain)
+	for i := 0; i < len(exp.Properties) && err == nil; i++ {
+		err = saveBase(c, domain, "property", exp.Properties[i].Name, &exp.Properties[i])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not keep the exported versions: %v\n", err)
+	}
+
+	return nil
+}
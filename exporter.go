@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/urfave/cli"
+)
+
+var exporterFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "listen",
+		Value: ":9799",
+		Usage: "The address to serve metrics on",
+	},
+	cli.StringFlag{
+		Name:  "path",
+		Value: "/metrics",
+		Usage: "The HTTP path to serve metrics on",
+	},
+	cli.DurationFlag{
+		Name:  "interval",
+		Value: time.Minute,
+		Usage: "How often to poll the GTM API",
+	},
+	cli.StringSliceFlag{
+		Name:  "domain",
+		Usage: "A Domain to export; may be repeated. Defaults to every Domain of the account",
+	},
+}
+
+// propagationStatuses are always exported for each domain, so that a
+// status that is not current reads 0 rather than being absent.
+var propagationStatuses = []string{"PENDING", "COMPLETE", "DENIED"}
+
+// lastModifiedLayouts are the formats the API uses for lastModified.
+var lastModifiedLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02T15:04:05-0700",
+}
+
+var (
+	upDesc = prometheus.NewDesc("akamai_gtm_up",
+		"Whether the last poll of the Domain succeeded.",
+		[]string{"domain"}, nil)
+	lastPollDesc = prometheus.NewDesc("akamai_gtm_last_poll_timestamp_seconds",
+		"When the Domain was last polled successfully.",
+		[]string{"domain"}, nil)
+	propagationDesc = prometheus.NewDesc("akamai_gtm_domain_propagation_status",
+		"The propagation status of the Domain's latest change: 1 for the current status, 0 for the others.",
+		[]string{"domain", "status"}, nil)
+	validationDesc = prometheus.NewDesc("akamai_gtm_domain_passing_validation",
+		"Whether the Domain passes validation.",
+		[]string{"domain"}, nil)
+	lastModifiedDesc = prometheus.NewDesc("akamai_gtm_domain_last_modified_timestamp_seconds",
+		"When the Domain was last modified.",
+		[]string{"domain"}, nil)
+	lastModifiedAgeDesc = prometheus.NewDesc("akamai_gtm_domain_last_modified_age_seconds",
+		"The time since the Domain was last modified.",
+		[]string{"domain"}, nil)
+	propertiesDesc = prometheus.NewDesc("akamai_gtm_domain_properties",
+		"The number of Properties in the Domain.",
+		[]string{"domain"}, nil)
+	dataCentersDesc = prometheus.NewDesc("akamai_gtm_domain_datacenters",
+		"The number of DataCenters in the Domain.",
+		[]string{"domain"}, nil)
+	targetsDesc = prometheus.NewDesc("akamai_gtm_datacenter_traffic_targets",
+		"The number of traffic targets in the DataCenter across the Domain's Properties, by whether they are enabled.",
+		[]string{"domain", "datacenter_id", "datacenter", "state"}, nil)
+)
+
+// exporterDomain is the state of a domain as of the last poll.
+type exporterDomain struct {
+	up           bool
+	polled       time.Time
+	status       *edgegrid.DomainStatus
+	lastModified time.Time
+	properties   int
+	dataCenters  []edgegrid.DataCenter
+	enabled      map[int]int
+	disabled     map[int]int
+}
+
+// exporterCollector polls domains and exports their state as of the last
+// poll when scraped.
+type exporterCollector struct {
+	api     gtm.API
+	domains []string
+
+	mu    sync.Mutex
+	state map[string]*exporterDomain
+}
+
+func exporter(c *cli.Context) error {
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "akamai_gtm_api_request_duration_seconds",
+		Help:    "The latency of GTM API calls, by API method.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"method"})
+	apiErrors := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "akamai_gtm_api_errors_total",
+		Help: "The number of GTM API calls that failed, by API method.",
+	}, []string{"method"})
+
+	api := gtm.Instrument(client(c), func(method string, duration time.Duration, err error) {
+		latency.WithLabelValues(method).Observe(duration.Seconds())
+		if err != nil {
+			apiErrors.WithLabelValues(method).Inc()
+		}
+	})
+	collector := &exporterCollector{
+		api:     api,
+		domains: c.StringSlice("domain"),
+		state:   map[string]*exporterDomain{},
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(latency, apiErrors, collector)
+	registry.MustRegister(prometheus.NewGoCollector())
+	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	go func() {
+		for {
+			collector.poll(logger)
+			time.Sleep(c.Duration("interval"))
+		}
+	}()
+
+	path := c.String("path")
+	mux := http.NewServeMux()
+	mux.Handle(path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: logger}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<html><head><title>akamai-gtm exporter</title></head><body><a href=%q>Metrics</a></body></html>\n", path)
+	})
+
+	logger.Printf("Serving metrics on %s%s", c.String("listen"), path)
+
+	return http.ListenAndServe(c.String("listen"), mux)
+}
+
+// poll refreshes the state of every domain, logging failures.
+func (e *exporterCollector) poll(logger *log.Logger) {
+	names := e.domains
+	if len(names) == 0 {
+		summaries, err := e.api.Domains()
+		if err != nil {
+			logger.Printf("Failed to list domains: %v", err)
+			return
+		}
+		names = []string{}
+		for _, summary := range summaries {
+			names = append(names, summary.Name)
+		}
+	}
+
+	polled := map[string]*exporterDomain{}
+	for _, name := range names {
+		state, err := e.pollDomain(name)
+		if err != nil {
+			logger.Printf("Failed to poll %s: %v", name, err)
+		}
+		polled[name] = state
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for name, state := range polled {
+		if state == nil {
+			// keep the last known state, marked down
+			state = e.state[name]
+			if state == nil {
+				state = &exporterDomain{}
+			}
+			state.up = false
+		}
+		polled[name] = state
+	}
+	e.state = polled
+}
+
+func (e *exporterCollector) pollDomain(name string) (*exporterDomain, error) {
+	domain, err := e.api.Domain(name)
+	if err != nil {
+		return nil, err
+	}
+	status, err := e.api.DomainStatus(name)
+	if err != nil {
+		return nil, err
+	}
+	dcs, err := e.api.DataCenters(name)
+	if err != nil {
+		return nil, err
+	}
+	props, err := e.api.Properties(name)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &exporterDomain{
+		up:          true,
+		polled:      time.Now(),
+		status:      status,
+		properties:  len(props.Properties),
+		dataCenters: dcs,
+		enabled:     map[int]int{},
+		disabled:    map[int]int{},
+	}
+	for _, layout := range lastModifiedLayouts {
+		if t, err := time.Parse(layout, domain.LastModified); err == nil {
+			state.lastModified = t
+			break
+		}
+	}
+	for _, prop := range props.Properties {
+		for _, target := range prop.TrafficTargets {
+			if target.Enabled {
+				state.enabled[target.DataCenterID]++
+			} else {
+				state.disabled[target.DataCenterID]++
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// Describe implements prometheus.Collector.
+func (e *exporterCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		upDesc, lastPollDesc, propagationDesc, validationDesc, lastModifiedDesc,
+		lastModifiedAgeDesc, propertiesDesc, dataCentersDesc, targetsDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (e *exporterCollector) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+	boolValue := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	for name, state := range e.state {
+		gauge(upDesc, boolValue(state.up), name)
+		if state.status == nil {
+			continue
+		}
+		gauge(lastPollDesc, float64(state.polled.Unix()), name)
+
+		current := state.status.PropagationStatus
+		known := false
+		for _, s := range propagationStatuses {
+			gauge(propagationDesc, boolValue(s == current), name, s)
+			known = known || s == current
+		}
+		if !known {
+			gauge(propagationDesc, 1, name, current)
+		}
+		gauge(validationDesc, boolValue(state.status.PassingValidation), name)
+
+		if !state.lastModified.IsZero() {
+			gauge(lastModifiedDesc, float64(state.lastModified.Unix()), name)
+			gauge(lastModifiedAgeDesc, time.Since(state.lastModified).Seconds(), name)
+		}
+		gauge(propertiesDesc, float64(state.properties), name)
+		gauge(dataCentersDesc, float64(len(state.dataCenters)), name)
+
+		for _, dc := range state.dataCenters {
+			id := strconv.Itoa(dc.DataCenterID)
+			gauge(targetsDesc, float64(state.enabled[dc.DataCenterID]), name, id, dc.Nickname, "enabled")
+			gauge(targetsDesc, float64(state.disabled[dc.DataCenterID]), name, id, dc.Nickname, "disabled")
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package gtm implements operations on Akamai GTM domains on top of a small
+// interface to the GTM API, so they can be reused outside of the CLI and
+// exercised against a fake.
+package gtm
+
+import (
+	"github.com/comcast/akamai-gtm/auth"
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// API is the subset of the GTM configuration API used by akamai-gtm. It is
+// satisfied by *edgegrid.GTMClient along with the calls it does not cover,
+// which Client makes; see NewClient.
+type API interface {
+	Domains() ([]edgegrid.DomainSummary, error)
+	Domain(name string) (*edgegrid.Domain, error)
+	// DomainDocument returns a domain in its generic JSON form, including
+	// the maps and resources that edgegrid.Domain does not model.
+	DomainDocument(name string) (map[string]interface{}, error)
+	DomainCreate(name, domainType string) (*edgegrid.DomainResponse, error)
+	// DomainCreateFrom creates a domain from a full definition in a
+	// contract and group; see Client.DomainCreateFrom.
+	DomainCreateFrom(definition interface{}, contract, group string) (*edgegrid.DomainResponse, error)
+	DomainUpdate(domain *edgegrid.Domain) (*edgegrid.DomainResponse, error)
+	DomainStatus(name string) (*edgegrid.DomainStatus, error)
+	DomainDelete(name string) (*edgegrid.DomainStatus, error)
+	// ObjectDelete deletes a named object of one of the collections
+	// Resources, GeographicMaps, CIDRMaps or ASMaps from a domain.
+	ObjectDelete(domain, collection, name string) (*edgegrid.DomainStatus, error)
+
+	DataCenters(domain string) ([]edgegrid.DataCenter, error)
+	DataCenter(domain string, id int) (*edgegrid.DataCenter, error)
+	DataCenterCreate(domain string, dc *edgegrid.DataCenter) (*edgegrid.DataCenterResponse, error)
+	DataCenterUpdate(domain string, dc *edgegrid.DataCenter) (*edgegrid.DataCenterResponse, error)
+	DataCenterDelete(domain string, id int) error
+
+	Properties(domain string) (*edgegrid.Properties, error)
+	Property(domain, name string) (*edgegrid.Property, error)
+	PropertyCreate(domain string, prop *edgegrid.Property) (*edgegrid.PropertyResponse, error)
+	PropertyUpdate(domain string, prop *edgegrid.Property) (*edgegrid.PropertyResponse, error)
+	PropertyDelete(domain, name string) (bool, error)
+
+	Contracts() ([]Contract, error)
+	Groups() ([]Group, error)
+}
+
+// client is an *edgegrid.GTMClient that makes the calls it does not cover
+// through a Client.
+type client struct {
+	*edgegrid.GTMClient
+	luna *Client
+}
+
+var _ API = (*client)(nil)
+
+func (c *client) DomainDocument(name string) (map[string]interface{}, error) {
+	return c.luna.DomainDocument(name)
+}
+
+func (c *client) DomainCreateFrom(definition interface{}, contract, group string) (*edgegrid.DomainResponse, error) {
+	return c.luna.DomainCreateFrom(definition, contract, group)
+}
+
+func (c *client) DomainDelete(name string) (*edgegrid.DomainStatus, error) {
+	return c.luna.DomainDelete(name)
+}
+
+func (c *client) ObjectDelete(domain, collection, name string) (*edgegrid.DomainStatus, error) {
+	return c.luna.ObjectDelete(domain, collection, name)
+}
+
+func (c *client) Contracts() ([]Contract, error) {
+	return c.luna.Contracts()
+}
+
+func (c *client) Groups() ([]Group, error) {
+	return c.luna.Groups()
+}
+
+// NewClient returns an API backed by the Luna API at host.
+func NewClient(accessToken, clientToken, clientSecret, host string) API {
+	return &client{
+		GTMClient: edgegrid.GTMClientWithCreds(accessToken, clientToken, clientSecret, host),
+		luna: &Client{
+			Host: host,
+			Credentials: auth.Credentials{
+				AccessToken:  accessToken,
+				ClientToken:  clientToken,
+				ClientSecret: clientSecret,
+			},
+		},
+	}
+}
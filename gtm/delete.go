@@ -0,0 +1,79 @@
+package gtm
+
+import "github.com/comcast/go-edgegrid/edgegrid"
+
+// Progress is called after each object of a bulk operation has been
+// processed, with the error if processing it failed.
+type Progress func(name string, err error)
+
+func (p Progress) report(name string, err error) {
+	if p != nil {
+		p(name, err)
+	}
+}
+
+// DeleteDataCenters deletes the given data centers in order, stopping at
+// the first failure. With check set, nothing is deleted if any of them is
+// still referenced; see CheckDataCentersUnused.
+func DeleteDataCenters(api API, domain string, dcs []edgegrid.DataCenter, check bool, progress Progress) error {
+	if check {
+		if err := CheckDataCentersUnused(api, domain, dcs); err != nil {
+			return err
+		}
+	}
+
+	for _, dc := range dcs {
+		err := api.DataCenterDelete(domain, dc.DataCenterID)
+		progress.report(dataCenterName(dc), err)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteAllDataCenters deletes every data center of a domain.
+func DeleteAllDataCenters(api API, domain string, check bool, progress Progress) error {
+	dcs, err := api.DataCenters(domain)
+	if err != nil {
+		return err
+	}
+
+	return DeleteDataCenters(api, domain, dcs, check, progress)
+}
+
+// DeleteProperties deletes the named properties in order, stopping at the
+// first failure.
+func DeleteProperties(api API, domain string, names []string, progress Progress) error {
+	for _, name := range names {
+		_, err := api.PropertyDelete(domain, name)
+		progress.report(name, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteAllProperties deletes every property of a domain. Failures are
+// reported to progress and do not stop the remaining deletes; the number
+// of properties that could not be deleted is returned.
+func DeleteAllProperties(api API, domain string, progress Progress) (int, error) {
+	props, err := api.Properties(domain)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for _, prop := range props.Properties {
+		_, err := api.PropertyDelete(domain, prop.Name)
+		progress.report(prop.Name, err)
+		if err != nil {
+			failed++
+		}
+	}
+
+	return failed, nil
+}
@@ -0,0 +1,612 @@
+// Package gtmfake provides an in-memory implementation of gtm.API for
+// exercising GTM operations without the Luna API.
+package gtmfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// firstDataCenterID is the ID given to the first data center created in a
+// domain.
+const firstDataCenterID = 3131
+
+// Client is an in-memory gtm.API. The zero value has no domains.
+type Client struct {
+	// Errors makes the named methods, e.g. "PropertyDelete", fail.
+	Errors map[string]error
+	// Calls records each method called, with its domain and object, e.g.
+	// "PropertyDelete example.akadns.net www".
+	Calls []string
+	// AvailableContracts and AvailableGroups are returned by Contracts and
+	// Groups.
+	AvailableContracts []gtm.Contract
+	AvailableGroups    []gtm.Group
+
+	mu      sync.Mutex
+	domains map[string]*domain
+}
+
+type domain struct {
+	domain      edgegrid.Domain
+	dataCenters map[int]edgegrid.DataCenter
+	properties  map[string]edgegrid.Property
+	changes     int
+	// fields holds the fields of the domain's generic JSON that
+	// edgegrid.Domain does not model, such as its maps.
+	fields map[string]interface{}
+}
+
+var _ gtm.API = (*Client)(nil)
+
+// New returns a Client holding the given domains, including their data
+// centers and properties.
+func New(domains ...edgegrid.Domain) *Client {
+	f := &Client{}
+	for _, d := range domains {
+		f.AddDomain(d)
+	}
+
+	return f
+}
+
+// AddDomain adds a domain along with its data centers and properties.
+func (f *Client) AddDomain(d edgegrid.Domain) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addDomain(d)
+}
+
+func (f *Client) addDomain(d edgegrid.Domain) *domain {
+	if f.domains == nil {
+		f.domains = map[string]*domain{}
+	}
+
+	dom := &domain{
+		dataCenters: map[int]edgegrid.DataCenter{},
+		properties:  map[string]edgegrid.Property{},
+		fields:      map[string]interface{}{},
+	}
+	for _, dc := range d.Datacenters {
+		dom.dataCenters[dc.DataCenterID] = dc
+	}
+	for _, prop := range d.Properties {
+		dom.properties[prop.Name] = prop
+	}
+	d.Datacenters = nil
+	d.Properties = nil
+	dom.domain = d
+	f.domains[d.Name] = dom
+
+	return dom
+}
+
+// SetDocumentField sets a field of a domain's generic JSON, as returned by
+// DomainDocument, that edgegrid.Domain does not model, e.g.
+// "geographicMaps".
+func (f *Client) SetDocumentField(name, field string, value interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d, err := f.domain(name)
+	if err != nil {
+		return err
+	}
+	d.fields[field] = value
+
+	return nil
+}
+
+// call records a method call and returns its injected error, if any.
+func (f *Client) call(method, domain string, object interface{}) error {
+	f.Calls = append(f.Calls, fmt.Sprintf("%s %s %v", method, domain, object))
+
+	return f.Errors[method]
+}
+
+// notFound returns the error the API gives for an object that does not
+// exist, for which gtm.IsNotFound is true.
+func notFound(format string, args ...interface{}) error {
+	return &gtm.APIError{StatusCode: http.StatusNotFound, Title: "Not Found", Detail: fmt.Sprintf(format, args...)}
+}
+
+func (f *Client) domain(name string) (*domain, error) {
+	d, ok := f.domains[name]
+	if !ok {
+		return nil, notFound("domain %s not found", name)
+	}
+
+	return d, nil
+}
+
+func (d *domain) status() *edgegrid.DomainStatus {
+	return &edgegrid.DomainStatus{
+		Message:           "Current configuration has been propagated to all GTM nameservers",
+		ChangeID:          fmt.Sprintf("change-%d", d.changes),
+		PropagationStatus: "COMPLETE",
+		PassingValidation: true,
+	}
+}
+
+func (d *domain) modified() *edgegrid.DomainStatus {
+	d.changes++
+
+	return d.status()
+}
+
+// full returns the domain with its data centers, properties and status.
+func (d *domain) full() *edgegrid.Domain {
+	dom := d.domain
+	dom.Datacenters = d.sortedDataCenters()
+	dom.Properties = d.sortedProperties()
+	dom.Status = d.status()
+
+	return &dom
+}
+
+func (d *domain) sortedDataCenters() []edgegrid.DataCenter {
+	dcs := []edgegrid.DataCenter{}
+	for _, dc := range d.dataCenters {
+		dcs = append(dcs, dc)
+	}
+	sort.Slice(dcs, func(i, j int) bool { return dcs[i].DataCenterID < dcs[j].DataCenterID })
+
+	return dcs
+}
+
+func (d *domain) sortedProperties() []edgegrid.Property {
+	props := []edgegrid.Property{}
+	for _, prop := range d.properties {
+		props = append(props, prop)
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+
+	return props
+}
+
+// Domains implements gtm.API.
+func (f *Client) Domains() ([]edgegrid.DomainSummary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Domains", "", ""); err != nil {
+		return nil, err
+	}
+
+	summaries := []edgegrid.DomainSummary{}
+	for name, d := range f.domains {
+		summaries = append(summaries, edgegrid.DomainSummary{
+			Name:         name,
+			Status:       d.status().PropagationStatus,
+			LastModified: d.domain.LastModified,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+
+	return summaries, nil
+}
+
+// Domain implements gtm.API.
+func (f *Client) Domain(name string) (*edgegrid.Domain, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Domain", name, ""); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.full(), nil
+}
+
+// DomainDocument implements gtm.API, adding the fields set with
+// SetDocumentField to the domain's JSON.
+func (f *Client) DomainDocument(name string) (map[string]interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DomainDocument", name, ""); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(d.full())
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for field, value := range d.fields {
+		doc[field] = value
+	}
+
+	// decode the fields as the API's response would be decoded
+	if data, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+	doc = map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// DomainCreate implements gtm.API.
+func (f *Client) DomainCreate(name, domainType string) (*edgegrid.DomainResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DomainCreate", name, domainType); err != nil {
+		return nil, err
+	}
+	if _, exists := f.domains[name]; exists {
+		return nil, fmt.Errorf("domain %s already exists", name)
+	}
+
+	d := f.addDomain(edgegrid.Domain{Name: name, Type: domainType})
+	dom := d.domain
+
+	return &edgegrid.DomainResponse{Domain: &dom, Status: d.modified()}, nil
+}
+
+// DomainCreateFrom implements gtm.API, keeping the maps and resources of
+// the definition as fields of the domain's generic JSON. The contract and
+// group are only recorded in Calls.
+func (f *Client) DomainCreateFrom(definition interface{}, contract, group string) (*edgegrid.DomainResponse, error) {
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return nil, err
+	}
+	dom := edgegrid.Domain{}
+	if err := json.Unmarshal(data, &dom); err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DomainCreateFrom", dom.Name, fmt.Sprintf("contract=%s group=%s", contract, group)); err != nil {
+		return nil, err
+	}
+	if _, exists := f.domains[dom.Name]; exists {
+		return nil, fmt.Errorf("domain %s already exists", dom.Name)
+	}
+
+	d := f.addDomain(dom)
+	for _, collection := range []string{gtm.Resources, gtm.GeographicMaps, gtm.CIDRMaps, gtm.ASMaps} {
+		if objects, ok := doc[gtm.CollectionKey(collection)]; ok {
+			d.fields[gtm.CollectionKey(collection)] = objects
+		}
+	}
+
+	return &edgegrid.DomainResponse{Domain: d.full(), Status: d.modified()}, nil
+}
+
+// DomainUpdate implements gtm.API. Data centers and properties included in
+// the domain replace the existing ones.
+func (f *Client) DomainUpdate(dom *edgegrid.Domain) (*edgegrid.DomainResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DomainUpdate", dom.Name, ""); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(dom.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *dom
+	if updated.Datacenters != nil {
+		d.dataCenters = map[int]edgegrid.DataCenter{}
+		for _, dc := range updated.Datacenters {
+			d.dataCenters[dc.DataCenterID] = dc
+		}
+	}
+	if updated.Properties != nil {
+		d.properties = map[string]edgegrid.Property{}
+		for _, prop := range updated.Properties {
+			d.properties[prop.Name] = prop
+		}
+	}
+	updated.Datacenters = nil
+	updated.Properties = nil
+	updated.Status = nil
+	d.domain = updated
+
+	return &edgegrid.DomainResponse{Domain: dom, Status: d.modified()}, nil
+}
+
+// DomainStatus implements gtm.API.
+func (f *Client) DomainStatus(name string) (*edgegrid.DomainStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DomainStatus", name, ""); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.status(), nil
+}
+
+// DomainDelete implements gtm.API.
+func (f *Client) DomainDelete(name string) (*edgegrid.DomainStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DomainDelete", name, ""); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(name)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(f.domains, name)
+
+	return d.modified(), nil
+}
+
+// ObjectDelete implements gtm.API, deleting an object set with
+// SetDocumentField.
+func (f *Client) ObjectDelete(domain, collection, name string) (*edgegrid.DomainStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ObjectDelete", domain, collection+" "+name); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return nil, err
+	}
+	key := gtm.CollectionKey(collection)
+	if key == "" {
+		return nil, fmt.Errorf("unknown collection %q", collection)
+	}
+
+	// the objects may have been set as any JSON-like value
+	data, err := json.Marshal(d.fields[key])
+	if err != nil {
+		return nil, err
+	}
+	objects := []map[string]interface{}{}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, err
+	}
+	kept := []interface{}{}
+	for _, obj := range objects {
+		if obj["name"] != name {
+			kept = append(kept, obj)
+		}
+	}
+	if len(kept) == len(objects) {
+		return nil, notFound("%s %s not found", collection, name)
+	}
+	d.fields[key] = kept
+
+	return d.modified(), nil
+}
+
+// DataCenters implements gtm.API.
+func (f *Client) DataCenters(domain string) ([]edgegrid.DataCenter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DataCenters", domain, ""); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.sortedDataCenters(), nil
+}
+
+// DataCenter implements gtm.API.
+func (f *Client) DataCenter(domain string, id int) (*edgegrid.DataCenter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DataCenter", domain, id); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return nil, err
+	}
+	dc, ok := d.dataCenters[id]
+	if !ok {
+		return nil, notFound("data center %d not found", id)
+	}
+
+	return &dc, nil
+}
+
+// DataCenterCreate implements gtm.API, assigning the data center an ID.
+func (f *Client) DataCenterCreate(domain string, dc *edgegrid.DataCenter) (*edgegrid.DataCenterResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DataCenterCreate", domain, dc.Nickname); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	created := *dc
+	created.DataCenterID = firstDataCenterID
+	for id := range d.dataCenters {
+		if id >= created.DataCenterID {
+			created.DataCenterID = id + 1
+		}
+	}
+	d.dataCenters[created.DataCenterID] = created
+
+	return &edgegrid.DataCenterResponse{DataCenter: &created, Status: d.modified()}, nil
+}
+
+// DataCenterUpdate implements gtm.API.
+func (f *Client) DataCenterUpdate(domain string, dc *edgegrid.DataCenter) (*edgegrid.DataCenterResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DataCenterUpdate", domain, dc.DataCenterID); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := d.dataCenters[dc.DataCenterID]; !ok {
+		return nil, notFound("data center %d not found", dc.DataCenterID)
+	}
+
+	updated := *dc
+	d.dataCenters[dc.DataCenterID] = updated
+
+	return &edgegrid.DataCenterResponse{DataCenter: &updated, Status: d.modified()}, nil
+}
+
+// DataCenterDelete implements gtm.API.
+func (f *Client) DataCenterDelete(domain string, id int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DataCenterDelete", domain, id); err != nil {
+		return err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return err
+	}
+	if _, ok := d.dataCenters[id]; !ok {
+		return notFound("data center %d not found", id)
+	}
+
+	delete(d.dataCenters, id)
+	d.modified()
+
+	return nil
+}
+
+// Properties implements gtm.API.
+func (f *Client) Properties(domain string) (*edgegrid.Properties, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Properties", domain, ""); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return &edgegrid.Properties{Properties: d.sortedProperties()}, nil
+}
+
+// Property implements gtm.API.
+func (f *Client) Property(domain, name string) (*edgegrid.Property, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Property", domain, name); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return nil, err
+	}
+	prop, ok := d.properties[name]
+	if !ok {
+		return nil, notFound("property %s not found", name)
+	}
+
+	return &prop, nil
+}
+
+// PropertyCreate implements gtm.API.
+func (f *Client) PropertyCreate(domain string, prop *edgegrid.Property) (*edgegrid.PropertyResponse, error) {
+	return f.putProperty("PropertyCreate", domain, prop)
+}
+
+// PropertyUpdate implements gtm.API.
+func (f *Client) PropertyUpdate(domain string, prop *edgegrid.Property) (*edgegrid.PropertyResponse, error) {
+	return f.putProperty("PropertyUpdate", domain, prop)
+}
+
+// putProperty creates or replaces a property, as the API's PUT does.
+func (f *Client) putProperty(method, domain string, prop *edgegrid.Property) (*edgegrid.PropertyResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(method, domain, prop.Name); err != nil {
+		return nil, err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range prop.TrafficTargets {
+		if _, ok := d.dataCenters[target.DataCenterID]; !ok {
+			return nil, fmt.Errorf("traffic target references unknown data center %d", target.DataCenterID)
+		}
+	}
+
+	stored := *prop
+	d.properties[prop.Name] = stored
+
+	return &edgegrid.PropertyResponse{Property: &stored, Status: d.modified()}, nil
+}
+
+// PropertyDelete implements gtm.API.
+func (f *Client) PropertyDelete(domain, name string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("PropertyDelete", domain, name); err != nil {
+		return false, err
+	}
+	d, err := f.domain(domain)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := d.properties[name]; !ok {
+		return false, notFound("property %s not found", name)
+	}
+
+	delete(d.properties, name)
+	d.modified()
+
+	return true, nil
+}
+
+// Contracts implements gtm.API.
+func (f *Client) Contracts() ([]gtm.Contract, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Contracts", "", ""); err != nil {
+		return nil, err
+	}
+
+	return f.AvailableContracts, nil
+}
+
+// Groups implements gtm.API.
+func (f *Client) Groups() ([]gtm.Group, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Groups", "", ""); err != nil {
+		return nil, err
+	}
+
+	return f.AvailableGroups, nil
+}
@@ -0,0 +1,133 @@
+package gtm
+
+import (
+	"time"
+
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// Observer is called after each call to the API with the name of the API
+// method, how long the call took and the error it returned.
+type Observer func(method string, duration time.Duration, err error)
+
+// Instrument returns an API that calls observe after each call to api,
+// e.g. to record latency and error metrics.
+func Instrument(api API, observe Observer) API {
+	return &instrumented{api: api, observe: observe}
+}
+
+type instrumented struct {
+	api     API
+	observe Observer
+}
+
+// done reports a call that started at start; it is deferred by each method
+// with a pointer to the method's named error result.
+func (i *instrumented) done(method string, start time.Time, err *error) {
+	i.observe(method, time.Since(start), *err)
+}
+
+func (i *instrumented) Domains() (result []edgegrid.DomainSummary, err error) {
+	defer i.done("Domains", time.Now(), &err)
+	return i.api.Domains()
+}
+
+func (i *instrumented) Domain(name string) (result *edgegrid.Domain, err error) {
+	defer i.done("Domain", time.Now(), &err)
+	return i.api.Domain(name)
+}
+
+func (i *instrumented) DomainDocument(name string) (result map[string]interface{}, err error) {
+	defer i.done("DomainDocument", time.Now(), &err)
+	return i.api.DomainDocument(name)
+}
+
+func (i *instrumented) DomainCreate(name, domainType string) (result *edgegrid.DomainResponse, err error) {
+	defer i.done("DomainCreate", time.Now(), &err)
+	return i.api.DomainCreate(name, domainType)
+}
+
+func (i *instrumented) DomainCreateFrom(definition interface{}, contract, group string) (result *edgegrid.DomainResponse, err error) {
+	defer i.done("DomainCreateFrom", time.Now(), &err)
+	return i.api.DomainCreateFrom(definition, contract, group)
+}
+
+func (i *instrumented) DomainUpdate(domain *edgegrid.Domain) (result *edgegrid.DomainResponse, err error) {
+	defer i.done("DomainUpdate", time.Now(), &err)
+	return i.api.DomainUpdate(domain)
+}
+
+func (i *instrumented) DomainStatus(name string) (result *edgegrid.DomainStatus, err error) {
+	defer i.done("DomainStatus", time.Now(), &err)
+	return i.api.DomainStatus(name)
+}
+
+func (i *instrumented) DomainDelete(name string) (result *edgegrid.DomainStatus, err error) {
+	defer i.done("DomainDelete", time.Now(), &err)
+	return i.api.DomainDelete(name)
+}
+
+func (i *instrumented) ObjectDelete(domain, collection, name string) (result *edgegrid.DomainStatus, err error) {
+	defer i.done("ObjectDelete", time.Now(), &err)
+	return i.api.ObjectDelete(domain, collection, name)
+}
+
+func (i *instrumented) DataCenters(domain string) (result []edgegrid.DataCenter, err error) {
+	defer i.done("DataCenters", time.Now(), &err)
+	return i.api.DataCenters(domain)
+}
+
+func (i *instrumented) DataCenter(domain string, id int) (result *edgegrid.DataCenter, err error) {
+	defer i.done("DataCenter", time.Now(), &err)
+	return i.api.DataCenter(domain, id)
+}
+
+func (i *instrumented) DataCenterCreate(domain string, dc *edgegrid.DataCenter) (result *edgegrid.DataCenterResponse, err error) {
+	defer i.done("DataCenterCreate", time.Now(), &err)
+	return i.api.DataCenterCreate(domain, dc)
+}
+
+func (i *instrumented) DataCenterUpdate(domain string, dc *edgegrid.DataCenter) (result *edgegrid.DataCenterResponse, err error) {
+	defer i.done("DataCenterUpdate", time.Now(), &err)
+	return i.api.DataCenterUpdate(domain, dc)
+}
+
+func (i *instrumented) DataCenterDelete(domain string, id int) (err error) {
+	defer i.done("DataCenterDelete", time.Now(), &err)
+	return i.api.DataCenterDelete(domain, id)
+}
+
+func (i *instrumented) Properties(domain string) (result *edgegrid.Properties, err error) {
+	defer i.done("Properties", time.Now(), &err)
+	return i.api.Properties(domain)
+}
+
+func (i *instrumented) Property(domain, name string) (result *edgegrid.Property, err error) {
+	defer i.done("Property", time.Now(), &err)
+	return i.api.Property(domain, name)
+}
+
+func (i *instrumented) PropertyCreate(domain string, prop *edgegrid.Property) (result *edgegrid.PropertyResponse, err error) {
+	defer i.done("PropertyCreate", time.Now(), &err)
+	return i.api.PropertyCreate(domain, prop)
+}
+
+func (i *instrumented) PropertyUpdate(domain string, prop *edgegrid.Property) (result *edgegrid.PropertyResponse, err error) {
+	defer i.done("PropertyUpdate", time.Now(), &err)
+	return i.api.PropertyUpdate(domain, prop)
+}
+
+func (i *instrumented) PropertyDelete(domain, name string) (result bool, err error) {
+	defer i.done("PropertyDelete", time.Now(), &err)
+	return i.api.PropertyDelete(domain, name)
+}
+
+func (i *instrumented) Contracts() (result []Contract, err error) {
+	defer i.done("Contracts", time.Now(), &err)
+	return i.api.Contracts()
+}
+
+func (i *instrumented) Groups() (result []Group, err error) {
+	defer i.done("Groups", time.Now(), &err)
+	return i.api.Groups()
+}
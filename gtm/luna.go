@@ -0,0 +1,228 @@
+package gtm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/auth"
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// Client makes the GTM configuration API calls that *edgegrid.GTMClient
+// does not cover, signing its requests with auth.Sign.
+type Client struct {
+	// Host is the Luna API host, optionally prefixed with a scheme;
+	// https is assumed.
+	Host        string
+	Credentials auth.Credentials
+	// HTTPClient is used to send requests; http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// the collections of domain objects that Client deletes; data centers and
+// properties are deleted through API
+const (
+	Resources      = "resources"
+	GeographicMaps = "geographic-maps"
+	CIDRMaps       = "cidr-maps"
+	ASMaps         = "as-maps"
+)
+
+// collectionKeys are the fields of a domain's generic JSON that hold the
+// objects of each collection.
+var collectionKeys = map[string]string{
+	Resources:      "resources",
+	GeographicMaps: "geographicMaps",
+	CIDRMaps:       "cidrMaps",
+	ASMaps:         "asMaps",
+}
+
+// CollectionKey returns the field of a domain's generic JSON, as returned
+// by DomainDocument, that holds the objects of a collection.
+func CollectionKey(collection string) string {
+	return collectionKeys[collection]
+}
+
+// Contract is a contract the API client may create domains in.
+type Contract struct {
+	ContractID   string `json:"contractId"`
+	ContractName string `json:"contractName,omitempty"`
+}
+
+// Group is a group the API client may create domains in.
+type Group struct {
+	GroupID     int      `json:"groupId"`
+	GroupName   string   `json:"groupName,omitempty"`
+	ContractIDs []string `json:"contractIds,omitempty"`
+}
+
+// APIError is an error response from the API.
+type APIError struct {
+	StatusCode int
+	Title      string `json:"title"`
+	Detail     string `json:"detail"`
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.Detail != "":
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Title, e.Detail)
+	case e.Title != "":
+		return fmt.Sprintf("%d %s", e.StatusCode, e.Title)
+	default:
+		return fmt.Sprintf("%d: %s", e.StatusCode, e.Body)
+	}
+}
+
+// Contracts lists the contracts available to the API client.
+func (c *Client) Contracts() ([]Contract, error) {
+	var resp struct {
+		Contracts []Contract `json:"contracts"`
+		Items     []Contract `json:"items"`
+	}
+	if err := c.do("GET", "/config-gtm/v1/identity/contracts", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return append(resp.Contracts, resp.Items...), nil
+}
+
+// Groups lists the groups available to the API client.
+func (c *Client) Groups() ([]Group, error) {
+	var resp struct {
+		Groups []Group `json:"groups"`
+		Items  []Group `json:"items"`
+	}
+	if err := c.do("GET", "/config-gtm/v1/identity/groups", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return append(resp.Groups, resp.Items...), nil
+}
+
+// DomainCreateFrom creates a domain from a full definition, which may hold
+// any domain setting the API accepts, in the given contract and group.
+// Either may be empty if the API client has access to a single one.
+func (c *Client) DomainCreateFrom(definition interface{}, contract, group string) (*edgegrid.DomainResponse, error) {
+	query := url.Values{}
+	if contract != "" {
+		query.Set("contractId", contract)
+	}
+	if group != "" {
+		query.Set("gid", group)
+	}
+	path := "/config-gtm/v1/domains"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp := &edgegrid.DomainResponse{}
+	if err := c.do("POST", path, definition, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DomainDocument returns a domain in its generic JSON form, including the
+// resources and maps that edgegrid.Domain leaves out.
+func (c *Client) DomainDocument(name string) (map[string]interface{}, error) {
+	doc := map[string]interface{}{}
+	if err := c.do("GET", "/config-gtm/v1/domains/"+url.PathEscape(name), nil, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// DomainDelete deletes a domain.
+func (c *Client) DomainDelete(name string) (*edgegrid.DomainStatus, error) {
+	var resp struct {
+		Status *edgegrid.DomainStatus `json:"status"`
+	}
+	if err := c.do("DELETE", "/config-gtm/v1/domains/"+url.PathEscape(name), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Status, nil
+}
+
+// ObjectDelete deletes a named object of one of the collections Resources,
+// GeographicMaps, CIDRMaps or ASMaps from a domain.
+func (c *Client) ObjectDelete(domain, collection, name string) (*edgegrid.DomainStatus, error) {
+	var resp struct {
+		Status *edgegrid.DomainStatus `json:"status"`
+	}
+	path := fmt.Sprintf("/config-gtm/v1/domains/%s/%s/%s", url.PathEscape(domain), collection, url.PathEscape(name))
+	if err := c.do("DELETE", path, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Status, nil
+}
+
+// IsNotFound returns whether err is an API response saying that the object
+// does not exist.
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	base := c.Host
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = "https://" + base
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(base, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err := auth.Sign(req, c.Credentials); err != nil {
+		return err
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+		json.Unmarshal(data, apiErr)
+		return apiErr
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,46 @@
+package gtm
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// Properties is a Property slice
+type Properties []Property
+
+// Property is an Akamai GTM property
+type Property struct {
+	GtmProperty edgegrid.Property
+	Product     string
+}
+
+func (props Properties) Len() int {
+	return len(props)
+}
+
+func (props Properties) Less(i, j int) bool {
+	return props[i].Product < props[j].Product
+}
+
+func (props Properties) Swap(i, j int) {
+	props[i], props[j] = props[j], props[i]
+}
+
+// SortedProperties returns the properties ordered by product, the last
+// label of their names.
+func SortedProperties(props *edgegrid.Properties) Properties {
+	created := Properties{}
+
+	for _, prop := range props.Properties {
+		splitName := strings.Split(prop.Name, ".")
+		created = append(created, Property{
+			GtmProperty: prop,
+			Product:     splitName[len(splitName)-1],
+		})
+	}
+	sort.Sort(created)
+
+	return created
+}
@@ -0,0 +1,169 @@
+package gtm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// mapTypes are the domain fields holding maps that assign data centers.
+var mapTypes = map[string]string{
+	"geographicMaps": "Geographic Map",
+	"cidrMaps":       "CIDR Map",
+	"asMaps":         "AS Map",
+}
+
+// Reference is an object that refers to a data center.
+type Reference struct {
+	Type   string
+	Name   string
+	Detail string
+}
+
+// SortReferences orders references by type, then name.
+func SortReferences(refs []Reference) {
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].Type != refs[j].Type {
+			return refs[i].Type > refs[j].Type
+		}
+		return refs[i].Name < refs[j].Name
+	})
+}
+
+// DataCenterReferences returns the properties and maps of a domain that
+// refer to each data center, keyed by data center ID.
+func DataCenterReferences(api API, domain string) (map[int][]Reference, error) {
+	refs := map[int][]Reference{}
+
+	props, err := api.Properties(domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, prop := range props.Properties {
+		for i, target := range prop.TrafficTargets {
+			state := "disabled"
+			if target.Enabled {
+				state = "enabled"
+			}
+			refs[target.DataCenterID] = append(refs[target.DataCenterID], Reference{
+				Type:   "Property",
+				Name:   prop.Name,
+				Detail: fmt.Sprintf("traffic target %d (%s)", i, state),
+			})
+		}
+	}
+
+	// maps are not modelled by edgegrid.Domain, so look for them in its
+	// generic JSON form
+	obj, err := api.DomainDocument(domain)
+	if err != nil {
+		return nil, err
+	}
+	for field, typ := range mapTypes {
+		maps, _ := obj[field].([]interface{})
+		for _, m := range maps {
+			for id, detail := range mapReferences(m) {
+				name, _ := m.(map[string]interface{})["name"].(string)
+				refs[id] = append(refs[id], Reference{Type: typ, Name: name, Detail: detail})
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+// mapReferences returns the data centers referenced by a map's default
+// data center and assignments.
+func mapReferences(m interface{}) map[int]string {
+	found := map[int]string{}
+	obj, ok := m.(map[string]interface{})
+	if !ok {
+		return found
+	}
+
+	if id, ok := documentInt(obj["defaultDatacenter"], "datacenterId"); ok {
+		found[id] = "default data center"
+	}
+	assignments, _ := obj["assignments"].([]interface{})
+	for _, a := range assignments {
+		if id, ok := documentInt(a, "datacenterId"); ok {
+			nickname, _ := a.(map[string]interface{})["nickname"].(string)
+			if found[id] != "" {
+				found[id] += ", "
+			}
+			found[id] += "assignment " + nickname
+		}
+	}
+
+	return found
+}
+
+func documentInt(v interface{}, key string) (int, bool) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	switch num := obj[key].(type) {
+	case float64:
+		return int(num), num == float64(int(num))
+	case json.Number:
+		i, err := strconv.Atoi(num.String())
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+// InUseError is returned when data centers that are still referenced would
+// be deleted.
+type InUseError struct {
+	DataCenters []edgegrid.DataCenter
+	References  map[int][]Reference
+}
+
+func (e *InUseError) Error() string {
+	names := []string{}
+	for _, dc := range e.DataCenters {
+		names = append(names, dataCenterName(dc))
+	}
+
+	return fmt.Sprintf("DataCenters still in use: %s", strings.Join(names, ", "))
+}
+
+// CheckDataCentersUnused returns an *InUseError if any of the given data
+// centers is referenced by a property or map of the domain.
+func CheckDataCentersUnused(api API, domain string, dcs []edgegrid.DataCenter) error {
+	refs, err := DataCenterReferences(api, domain)
+	if err != nil {
+		return err
+	}
+
+	inUse := &InUseError{References: map[int][]Reference{}}
+	for _, dc := range dcs {
+		if len(refs[dc.DataCenterID]) == 0 {
+			continue
+		}
+		inUse.DataCenters = append(inUse.DataCenters, dc)
+		inUse.References[dc.DataCenterID] = refs[dc.DataCenterID]
+	}
+
+	if len(inUse.DataCenters) != 0 {
+		return inUse
+	}
+
+	return nil
+}
+
+// dataCenterName is the nickname and ID of a data center, or just the ID
+// when the nickname is unknown.
+func dataCenterName(dc edgegrid.DataCenter) string {
+	if dc.Nickname == "" {
+		return strconv.Itoa(dc.DataCenterID)
+	}
+
+	return fmt.Sprintf("%s (%d)", dc.Nickname, dc.DataCenterID)
+}
@@ -0,0 +1,120 @@
+package gtm_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/akamai-gtm/gtm/gtmfake"
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+const testDomain = "example.akadns.net"
+
+// newReferencesFake returns a domain in which data center 3131 is used by
+// a geographic map, 3132 by a property and 3133 by nothing.
+func newReferencesFake(t *testing.T) *gtmfake.Client {
+	fake := gtmfake.New(edgegrid.Domain{
+		Name: testDomain,
+		Type: "full",
+		Datacenters: []edgegrid.DataCenter{
+			{DataCenterID: 3131, Nickname: "east"},
+			{DataCenterID: 3132, Nickname: "west"},
+			{DataCenterID: 3133, Nickname: "spare"},
+		},
+		Properties: []edgegrid.Property{{
+			Name: "www",
+			Type: "weighted-round-robin",
+			TrafficTargets: []edgegrid.TrafficTarget{
+				{DataCenterID: 3132, Enabled: true, Weight: 1},
+			},
+		}},
+	})
+	err := fake.SetDocumentField(testDomain, "geographicMaps", []interface{}{
+		map[string]interface{}{
+			"name":              "geo",
+			"defaultDatacenter": map[string]interface{}{"datacenterId": 3131, "nickname": "east"},
+			"assignments": []interface{}{
+				map[string]interface{}{"datacenterId": 3131, "nickname": "europe", "countries": []string{"FR"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fake
+}
+
+func TestDataCenterReferences(t *testing.T) {
+	refs, err := gtm.DataCenterReferences(newReferencesFake(t), testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   int
+		want []gtm.Reference
+	}{
+		{3131, []gtm.Reference{{Type: "Geographic Map", Name: "geo", Detail: "default data center, assignment europe"}}},
+		{3132, []gtm.Reference{{Type: "Property", Name: "www", Detail: "traffic target 0 (enabled)"}}},
+		{3133, nil},
+	}
+	for _, test := range tests {
+		if got := refs[test.id]; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("references to %d = %+v, want %+v", test.id, got, test.want)
+		}
+	}
+}
+
+func TestDeleteDataCentersInUse(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []int
+		check   bool
+		inUse   []int
+		deleted []int
+	}{
+		{name: "used by a map", ids: []int{3131}, check: true, inUse: []int{3131}},
+		{name: "used by a property", ids: []int{3133, 3132}, check: true, inUse: []int{3132}},
+		{name: "unused", ids: []int{3133}, check: true, deleted: []int{3133}},
+		{name: "forced", ids: []int{3131, 3132}, check: false, deleted: []int{3131, 3132}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newReferencesFake(t)
+			dcs := []edgegrid.DataCenter{}
+			for _, id := range test.ids {
+				dcs = append(dcs, edgegrid.DataCenter{DataCenterID: id})
+			}
+
+			err := gtm.DeleteDataCenters(fake, testDomain, dcs, test.check, nil)
+			if test.inUse != nil {
+				inUse, ok := err.(*gtm.InUseError)
+				if !ok {
+					t.Fatalf("error = %v, want an *InUseError", err)
+				}
+				got := []int{}
+				for _, dc := range inUse.DataCenters {
+					got = append(got, dc.DataCenterID)
+				}
+				if !reflect.DeepEqual(got, test.inUse) {
+					t.Errorf("in use = %v, want %v", got, test.inUse)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, id := range test.ids {
+				_, err := fake.DataCenter(testDomain, id)
+				wasDeleted := false
+				for _, d := range test.deleted {
+					wasDeleted = wasDeleted || d == id
+				}
+				if exists := err == nil; exists == wasDeleted {
+					t.Errorf("data center %d exists = %v, want %v", id, exists, !wasDeleted)
+				}
+			}
+		})
+	}
+}
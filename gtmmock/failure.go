@@ -0,0 +1,91 @@
+package gtmmock
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Failure makes the server fail matching requests with an error status.
+type Failure struct {
+	// Status is the HTTP status to respond with, e.g. 429, 500 or 400 for
+	// a validation error.
+	Status int
+	// Method restricts the failure to one HTTP method; empty matches all.
+	Method string
+	// Path restricts the failure to request paths matching a regular
+	// expression; nil matches all.
+	Path *regexp.Regexp
+	// Rate is the probability of failing a matching request; 0 never
+	// fails and 1 always does.
+	Rate float64
+	// Count limits the number of failures; 0 is unlimited.
+	Count int
+
+	mu   sync.Mutex
+	hits int
+}
+
+// ParseFailure parses a failure specification of the form
+// <status>[,method=<method>][,path=<regexp>][,rate=<0-1>][,count=<n>],
+// e.g. "429,method=PUT,path=/properties/,rate=0.5". The rate defaults to 1.
+func ParseFailure(spec string) (*Failure, error) {
+	parts := strings.Split(spec, ",")
+	status, err := strconv.Atoi(parts[0])
+	if err != nil || status < 400 || status > 599 {
+		return nil, fmt.Errorf("invalid failure %q: status must be 400-599", spec)
+	}
+
+	f := &Failure{Status: status, Rate: 1}
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid failure %q: expected key=value, got %q", spec, part)
+		}
+		switch kv[0] {
+		case "method":
+			f.Method = strings.ToUpper(kv[1])
+		case "path":
+			if f.Path, err = regexp.Compile(kv[1]); err != nil {
+				return nil, fmt.Errorf("invalid failure %q: %v", spec, err)
+			}
+		case "rate":
+			if f.Rate, err = strconv.ParseFloat(kv[1], 64); err != nil || f.Rate < 0 || f.Rate > 1 {
+				return nil, fmt.Errorf("invalid failure %q: rate must be between 0 and 1", spec)
+			}
+		case "count":
+			if f.Count, err = strconv.Atoi(kv[1]); err != nil || f.Count < 0 {
+				return nil, fmt.Errorf("invalid failure %q: invalid count", spec)
+			}
+		default:
+			return nil, fmt.Errorf("invalid failure %q: unknown key %q", spec, kv[0])
+		}
+	}
+
+	return f, nil
+}
+
+// trigger reports whether the failure applies to req.
+func (f *Failure) trigger(req *http.Request, rnd func() float64) bool {
+	if f.Method != "" && f.Method != req.Method {
+		return false
+	}
+	if f.Path != nil && !f.Path.MatchString(req.URL.Path) {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Count != 0 && f.hits >= f.Count {
+		return false
+	}
+	if rnd() >= f.Rate {
+		return false
+	}
+	f.hits++
+
+	return true
+}
@@ -0,0 +1,47 @@
+package gtmmock
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFailureRate(t *testing.T) {
+	tests := []struct {
+		spec  string
+		rnd   float64
+		fails bool
+	}{
+		{spec: "500", rnd: 0.99, fails: true},
+		{spec: "500,rate=1", rnd: 0.99, fails: true},
+		{spec: "500,rate=0", rnd: 0, fails: false},
+		{spec: "500,rate=0.5", rnd: 0.25, fails: true},
+		{spec: "500,rate=0.5", rnd: 0.5, fails: false},
+	}
+	for _, test := range tests {
+		f, err := ParseFailure(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/config-gtm/v1/domains", nil)
+		if got := f.trigger(req, func() float64 { return test.rnd }); got != test.fails {
+			t.Errorf("%s with random %v fails = %v, want %v", test.spec, test.rnd, got, test.fails)
+		}
+	}
+}
+
+func TestFailureCount(t *testing.T) {
+	f, err := ParseFailure("400,method=PUT,count=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := func() float64 { return 0 }
+	if f.trigger(httptest.NewRequest("GET", "/", nil), rnd) {
+		t.Error("GET failed, want only PUT to fail")
+	}
+	if !f.trigger(httptest.NewRequest("PUT", "/", nil), rnd) {
+		t.Error("first PUT did not fail")
+	}
+	if f.trigger(httptest.NewRequest("PUT", "/", nil), rnd) {
+		t.Error("second PUT failed, want count=1")
+	}
+}
@@ -0,0 +1,727 @@
+// Package gtmmock is an in-memory emulator of the Akamai GTM configuration
+// API, for exercising clients without touching a real account.
+package gtmmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comcast/akamai-gtm/auth"
+)
+
+// BasePath is the path prefix of the GTM configuration API.
+const BasePath = "/config-gtm/v1"
+
+// MaxSkew is the allowed difference between a signed request's timestamp
+// and the server clock.
+const MaxSkew = 5 * time.Minute
+
+// the contract and group the mock offers to create domains in
+const (
+	ContractID = "1-MOCK"
+	GroupID    = 10000
+)
+
+// Server serves the GTM configuration API from in-memory state.
+type Server struct {
+	// PropagationDelay is how long a change stays PENDING before the
+	// domain status reports it COMPLETE.
+	PropagationDelay time.Duration
+	// Credentials, if set, are required to have signed every request.
+	Credentials *auth.Credentials
+	// Failures are injected in place of matching requests' responses.
+	Failures []*Failure
+	// Logger, if set, logs every request.
+	Logger *log.Logger
+
+	mu      sync.Mutex
+	domains map[string]*domain
+	rnd     *rand.Rand
+	changes int
+}
+
+// New returns a Server with no domains.
+func New() *Server {
+	return &Server{
+		domains: map[string]*domain{},
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Load reads domains from dir, either an export directory as written by
+// "akamai-gtm export" or a directory of them. If persist is true, changes
+// are written back to the directories they were loaded from.
+func (s *Server) Load(dir string, persist bool) error {
+	domains, err := loadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range domains {
+		if !persist {
+			d.dir = ""
+		}
+		s.domains[d.name()] = d
+	}
+
+	return nil
+}
+
+// AddDomain creates an empty domain.
+func (s *Server) AddDomain(name, typ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := newDomain(object{"name": name, "type": typ})
+	s.modified(d)
+	s.domains[name] = d
+}
+
+// Domains returns the names of the served domains.
+func (s *Server) Domains() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := []string{}
+	for name := range s.domains {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// apiError is an error response in the API's problem JSON format.
+type apiError struct {
+	Type   string            `json:"type"`
+	Title  string            `json:"title"`
+	Status int               `json:"status"`
+	Detail string            `json:"detail,omitempty"`
+	Errors []validationError `json:"errors,omitempty"`
+}
+
+type validationError struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+func (e *apiError) Error() string {
+	if e.Detail == "" {
+		return e.Title
+	}
+
+	return e.Title + ": " + e.Detail
+}
+
+func newError(status int, detail string, args ...interface{}) *apiError {
+	return &apiError{
+		Type:   "https://problems.luna.akamaiapis.net/config-gtm/v1/" + strings.ToLower(strings.Replace(http.StatusText(status), " ", "-", -1)),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: fmt.Sprintf(detail, args...),
+	}
+}
+
+func validationFailed(details ...string) *apiError {
+	e := newError(http.StatusBadRequest, "")
+	e.Type = "https://problems.luna.akamaiapis.net/config-gtm/v1/validation-failed"
+	e.Title = "Validation Failed"
+	for _, detail := range details {
+		e.Errors = append(e.Errors, validationError{
+			Type:   "https://problems.luna.akamaiapis.net/config-gtm/v1/validation-error",
+			Title:  "Validation Error",
+			Detail: detail,
+		})
+	}
+	if len(details) > 0 {
+		e.Detail = details[0]
+	}
+
+	return e
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	status, body := s.handle(req)
+	if s.Logger != nil {
+		s.Logger.Printf("%s %s %d", req.Method, req.URL.RequestURI(), status)
+	}
+
+	if e, ok := body.(*apiError); ok {
+		w.Header().Set("Content-Type", "application/problem+json")
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		e.Status = status
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	if body != nil {
+		data, _ := json.MarshalIndent(body, "", "  ")
+		w.Write(append(data, '\n'))
+	}
+}
+
+func (s *Server) handle(req *http.Request) (int, interface{}) {
+	if s.Credentials != nil {
+		if err := auth.Verify(req, *s.Credentials, MaxSkew); err != nil {
+			return http.StatusUnauthorized, newError(http.StatusUnauthorized, "The signature does not match: %v", err)
+		}
+	}
+
+	for _, f := range s.Failures {
+		if f.trigger(req, s.random) {
+			if f.Status == http.StatusBadRequest {
+				return f.Status, validationFailed("injected validation failure")
+			}
+			return f.Status, newError(f.Status, "injected failure")
+		}
+	}
+
+	if strings.HasPrefix(req.URL.Path, BasePath+"/identity/") {
+		status, body, err := identityHandler(req)
+		if err != nil {
+			return err.Status, err
+		}
+		return status, body
+	}
+	if !strings.HasPrefix(req.URL.Path, BasePath+"/domains") {
+		return http.StatusNotFound, newError(http.StatusNotFound, "no such resource %s", req.URL.Path)
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, BasePath), "/"), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		status int
+		body   interface{}
+		err    *apiError
+	)
+	switch {
+	case len(parts) == 1:
+		status, body, err = s.domainsHandler(req)
+	case len(parts) == 2:
+		status, body, err = s.domainHandler(req, parts[1])
+	case len(parts) == 4 && parts[2] == "status" && parts[3] == "current":
+		status, body, err = s.statusHandler(req, parts[1])
+	case len(parts) == 3 && parts[2] == "datacenters":
+		status, body, err = s.dataCentersHandler(req, parts[1])
+	case len(parts) == 4 && parts[2] == "datacenters":
+		status, body, err = s.dataCenterHandler(req, parts[1], parts[3])
+	case len(parts) == 3 && parts[2] == "properties":
+		status, body, err = s.propertiesHandler(req, parts[1])
+	case len(parts) == 4 && parts[2] == "properties":
+		status, body, err = s.propertyHandler(req, parts[1], parts[3])
+	case len(parts) == 4 && domainCollections[parts[2]] != "":
+		status, body, err = s.collectionObjectHandler(req, parts[1], domainCollections[parts[2]], parts[3])
+	default:
+		err = newError(http.StatusNotFound, "no such resource %s", req.URL.Path)
+	}
+	if err != nil {
+		return err.Status, err
+	}
+
+	return status, body
+}
+
+func (s *Server) random() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rnd.Float64()
+}
+
+func methodNotAllowed(req *http.Request) *apiError {
+	return newError(http.StatusMethodNotAllowed, "%s is not supported on %s", req.Method, req.URL.Path)
+}
+
+func (s *Server) domain(name string) (*domain, *apiError) {
+	d, ok := s.domains[name]
+	if !ok {
+		return nil, newError(http.StatusNotFound, "domain %s does not exist", name)
+	}
+
+	return d, nil
+}
+
+// status returns the domain's propagation status, which turns COMPLETE
+// once PropagationDelay has passed since the last change.
+func (s *Server) status(d *domain) object {
+	status := object{
+		"changeId":              d.changeID,
+		"passingValidation":     true,
+		"propagationStatus":     "COMPLETE",
+		"propagationStatusDate": d.modifiedAt.Add(s.PropagationDelay).Format(time.RFC3339),
+		"message":               "Current configuration has been propagated to all GTM nameservers",
+		"links":                 []interface{}{},
+	}
+	if time.Since(d.modifiedAt) < s.PropagationDelay {
+		status["propagationStatus"] = "PENDING"
+		status["propagationStatusDate"] = d.modifiedAt.Format(time.RFC3339)
+		status["message"] = "Change Pending"
+	}
+
+	return status
+}
+
+// modified records a change to d, restarting propagation.
+func (s *Server) modified(d *domain) {
+	s.changes++
+	d.modifiedAt = time.Now()
+	d.changeID = fmt.Sprintf("%08x-%04x-4000-8000-%012x", d.modifiedAt.Unix(), s.changes, s.rnd.Int63()&0xffffffffffff)
+	d.resource["lastModified"] = d.modifiedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// commit finishes a mutation of d, persisting it and returning the
+// response body for resource.
+func (s *Server) commit(d *domain, resource interface{}) (object, *apiError) {
+	s.modified(d)
+	if err := d.save(); err != nil {
+		return nil, newError(http.StatusInternalServerError, "could not persist domain %s: %v", d.name(), err)
+	}
+
+	return object{"resource": resource, "status": s.status(d)}, nil
+}
+
+func decodeObject(req *http.Request) (object, *apiError) {
+	obj := object{}
+	if err := json.NewDecoder(req.Body).Decode(&obj); err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid JSON: %v", err)
+	}
+
+	return obj, nil
+}
+
+func (s *Server) domainsHandler(req *http.Request) (int, interface{}, *apiError) {
+	switch req.Method {
+	case "GET":
+		items := []object{}
+		for _, name := range sortedKeys(s.domains) {
+			d := s.domains[name]
+			items = append(items, object{
+				"name":         name,
+				"status":       s.status(d)["propagationStatus"],
+				"lastModified": d.resource["lastModified"],
+			})
+		}
+		return http.StatusOK, object{"items": items}, nil
+	case "POST":
+		if contract := req.URL.Query().Get("contractId"); contract != "" && contract != ContractID {
+			return 0, nil, newError(http.StatusForbidden, "no access to contract %s", contract)
+		}
+		if group := req.URL.Query().Get("gid"); group != "" && group != strconv.Itoa(GroupID) {
+			return 0, nil, newError(http.StatusForbidden, "no access to group %s", group)
+		}
+		obj, err := decodeObject(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		name, _ := obj["name"].(string)
+		if _, exists := s.domains[name]; exists {
+			return 0, nil, newError(http.StatusConflict, "domain %s already exists", name)
+		}
+		return s.putDomain(name, obj)
+	default:
+		return 0, nil, methodNotAllowed(req)
+	}
+}
+
+// identityHandler serves the contract and group the mock offers.
+func identityHandler(req *http.Request) (int, interface{}, *apiError) {
+	if req.Method != "GET" {
+		return 0, nil, methodNotAllowed(req)
+	}
+	switch strings.TrimPrefix(req.URL.Path, BasePath+"/identity/") {
+	case "contracts":
+		return http.StatusOK, object{"contracts": []object{
+			{"contractId": ContractID, "contractName": "Mock contract"},
+		}}, nil
+	case "groups":
+		return http.StatusOK, object{"groups": []object{
+			{"groupId": GroupID, "groupName": "Mock group", "contractIds": []string{ContractID}},
+		}}, nil
+	}
+
+	return 0, nil, newError(http.StatusNotFound, "no such resource %s", req.URL.Path)
+}
+
+func (s *Server) domainHandler(req *http.Request, name string) (int, interface{}, *apiError) {
+	switch req.Method {
+	case "GET":
+		d, err := s.domain(name)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, d.full(s.status(d)), nil
+	case "PUT":
+		obj, err := decodeObject(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		return s.putDomain(name, obj)
+	case "DELETE":
+		d, err := s.domain(name)
+		if err != nil {
+			return 0, nil, err
+		}
+		contents := len(d.dataCenters) + len(d.properties)
+		for _, key := range domainCollections {
+			contents += len(objects(d.resource[key]))
+		}
+		if contents > 0 {
+			return 0, nil, validationFailed(fmt.Sprintf("domain %s still contains %d objects", name, contents))
+		}
+		// an export directory the domain was loaded from is left in place
+		delete(s.domains, name)
+		s.modified(d)
+		return http.StatusOK, object{"resource": nil, "status": s.status(d)}, nil
+	default:
+		return 0, nil, methodNotAllowed(req)
+	}
+}
+
+// domainCollections maps the paths of the domain objects that the mock
+// keeps in the domain resource to their keys in it.
+var domainCollections = map[string]string{
+	"resources":       "resources",
+	"geographic-maps": "geographicMaps",
+	"cidr-maps":       "cidrMaps",
+	"as-maps":         "asMaps",
+}
+
+// collectionObjectHandler serves the objects kept in a list of the domain
+// resource, which may be read and deleted.
+func (s *Server) collectionObjectHandler(req *http.Request, name, key, objName string) (int, interface{}, *apiError) {
+	d, err := s.domain(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	items := objects(d.resource[key])
+	index := -1
+	for i, item := range items {
+		if item["name"] == objName {
+			index = i
+		}
+	}
+	if index < 0 {
+		return 0, nil, newError(http.StatusNotFound, "%s %s does not exist in domain %s", key, objName, name)
+	}
+
+	switch req.Method {
+	case "GET":
+		return http.StatusOK, items[index], nil
+	case "DELETE":
+		kept := []interface{}{}
+		for i, item := range items {
+			if i != index {
+				kept = append(kept, item)
+			}
+		}
+		d.resource[key] = kept
+		body, err := s.commit(d, nil)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, body, nil
+	default:
+		return 0, nil, methodNotAllowed(req)
+	}
+}
+
+func (s *Server) putDomain(name string, obj object) (int, interface{}, *apiError) {
+	if name == "" {
+		return 0, nil, validationFailed("domain name is required")
+	}
+	if objName, ok := obj["name"].(string); ok && objName != name {
+		return 0, nil, validationFailed(fmt.Sprintf("domain name %q does not match %q", objName, name))
+	}
+	switch obj["type"] {
+	case "basic", "full", "weighted", "static", "failover-only":
+	default:
+		return 0, nil, validationFailed(fmt.Sprintf("invalid domain type %v", obj["type"]))
+	}
+	obj["name"] = name
+
+	status := http.StatusOK
+	d, ok := s.domains[name]
+	if !ok {
+		status = http.StatusCreated
+		d = newDomain(obj)
+		s.domains[name] = d
+	}
+
+	// data centers and properties are managed through their own endpoints
+	dcs, _ := obj["datacenters"].([]interface{})
+	props, _ := obj["properties"].([]interface{})
+	delete(obj, "datacenters")
+	delete(obj, "properties")
+	delete(obj, "status")
+	d.resource = obj
+	for _, dc := range dcs {
+		if dc, ok := dc.(map[string]interface{}); ok {
+			if id, ok := intField(dc, "datacenterId"); ok {
+				d.dataCenters[id] = dc
+			}
+		}
+	}
+	for _, prop := range props {
+		if prop, ok := prop.(map[string]interface{}); ok {
+			if name, ok := prop["name"].(string); ok {
+				d.properties[name] = prop
+			}
+		}
+	}
+
+	body, err := s.commit(d, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	body["resource"] = d.full(body["status"].(object))
+
+	return status, body, nil
+}
+
+func (s *Server) statusHandler(req *http.Request, name string) (int, interface{}, *apiError) {
+	if req.Method != "GET" {
+		return 0, nil, methodNotAllowed(req)
+	}
+	d, err := s.domain(name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, s.status(d), nil
+}
+
+func (s *Server) dataCentersHandler(req *http.Request, name string) (int, interface{}, *apiError) {
+	d, err := s.domain(name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch req.Method {
+	case "GET":
+		return http.StatusOK, object{"items": d.sortedDataCenters()}, nil
+	case "POST":
+		dc, err := decodeObject(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		id := d.nextDataCenterID()
+		dc["datacenterId"] = id
+		if err := validateDataCenter(d, dc); err != nil {
+			return 0, nil, err
+		}
+		d.dataCenters[id] = dc
+		body, err := s.commit(d, dc)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, body, nil
+	default:
+		return 0, nil, methodNotAllowed(req)
+	}
+}
+
+func (s *Server) dataCenterHandler(req *http.Request, name, idStr string) (int, interface{}, *apiError) {
+	d, err := s.domain(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	id, convErr := strconv.Atoi(idStr)
+	dc, ok := d.dataCenters[id]
+	if convErr != nil || (!ok && req.Method != "PUT") {
+		return 0, nil, newError(http.StatusNotFound, "data center %s does not exist in domain %s", idStr, name)
+	}
+
+	switch req.Method {
+	case "GET":
+		return http.StatusOK, dc, nil
+	case "PUT":
+		dc, err := decodeObject(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		if bodyID, ok := intField(dc, "datacenterId"); ok && bodyID != id {
+			return 0, nil, validationFailed(fmt.Sprintf("datacenterId %d does not match %d", bodyID, id))
+		}
+		dc["datacenterId"] = id
+		if err := validateDataCenter(d, dc); err != nil {
+			return 0, nil, err
+		}
+		status := http.StatusOK
+		if !ok {
+			status = http.StatusCreated
+		}
+		d.dataCenters[id] = dc
+		body, err := s.commit(d, dc)
+		if err != nil {
+			return 0, nil, err
+		}
+		return status, body, nil
+	case "DELETE":
+		for _, prop := range d.sortedProperties() {
+			for _, target := range objects(prop["trafficTargets"]) {
+				if targetID, _ := intField(target, "datacenterId"); targetID == id {
+					return 0, nil, validationFailed(fmt.Sprintf("data center %d is referenced by property %v", id, prop["name"]))
+				}
+			}
+		}
+		delete(d.dataCenters, id)
+		body, err := s.commit(d, nil)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, body, nil
+	default:
+		return 0, nil, methodNotAllowed(req)
+	}
+}
+
+func (s *Server) propertiesHandler(req *http.Request, name string) (int, interface{}, *apiError) {
+	if req.Method != "GET" {
+		return 0, nil, methodNotAllowed(req)
+	}
+	d, err := s.domain(name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, object{"items": d.sortedProperties()}, nil
+}
+
+func (s *Server) propertyHandler(req *http.Request, name, propName string) (int, interface{}, *apiError) {
+	d, err := s.domain(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	prop, ok := d.properties[propName]
+
+	switch req.Method {
+	case "GET":
+		if !ok {
+			return 0, nil, newError(http.StatusNotFound, "property %s does not exist in domain %s", propName, name)
+		}
+		return http.StatusOK, prop, nil
+	case "PUT":
+		prop, err := decodeObject(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		if bodyName, ok := prop["name"].(string); ok && bodyName != propName {
+			return 0, nil, validationFailed(fmt.Sprintf("property name %q does not match %q", bodyName, propName))
+		}
+		prop["name"] = propName
+		if err := validateProperty(d, prop); err != nil {
+			return 0, nil, err
+		}
+		status := http.StatusOK
+		if !ok {
+			status = http.StatusCreated
+		}
+		prop["lastModified"] = time.Now().UTC().Format(time.RFC3339Nano)
+		d.properties[propName] = prop
+		body, err := s.commit(d, prop)
+		if err != nil {
+			return 0, nil, err
+		}
+		return status, body, nil
+	case "DELETE":
+		if !ok {
+			return 0, nil, newError(http.StatusNotFound, "property %s does not exist in domain %s", propName, name)
+		}
+		delete(d.properties, propName)
+		body, err := s.commit(d, nil)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, body, nil
+	default:
+		return 0, nil, methodNotAllowed(req)
+	}
+}
+
+func validateDataCenter(d *domain, dc object) *apiError {
+	problems := []string{}
+	if nickname, _ := dc["nickname"].(string); nickname == "" {
+		problems = append(problems, "nickname is required")
+	}
+	for id, other := range d.dataCenters {
+		if dcID, _ := intField(dc, "datacenterId"); id != dcID && other["nickname"] == dc["nickname"] {
+			problems = append(problems, fmt.Sprintf("nickname %v is already used by data center %d", dc["nickname"], id))
+		}
+	}
+	if len(problems) > 0 {
+		return validationFailed(problems...)
+	}
+
+	return nil
+}
+
+func validateProperty(d *domain, prop object) *apiError {
+	problems := []string{}
+	switch prop["type"] {
+	case "failover", "geographic", "cidrmapping", "asmapping", "performance", "qtr",
+		"weighted-round-robin", "weighted-hashed", "weighted-round-robin-load-feedback":
+	default:
+		problems = append(problems, fmt.Sprintf("invalid property type %v", prop["type"]))
+	}
+
+	for _, target := range objects(prop["trafficTargets"]) {
+		id, ok := intField(target, "datacenterId")
+		if !ok {
+			problems = append(problems, "traffic target has no datacenterId")
+			continue
+		}
+		if _, exists := d.dataCenters[id]; !exists {
+			problems = append(problems, fmt.Sprintf("traffic target references unknown data center %d", id))
+		}
+	}
+
+	for _, test := range objects(prop["livenessTests"]) {
+		if name, _ := test["name"].(string); name == "" {
+			problems = append(problems, "liveness test name is required")
+		}
+		if protocol, _ := test["testObjectProtocol"].(string); protocol == "" {
+			problems = append(problems, fmt.Sprintf("liveness test %v has no testObjectProtocol", test["name"]))
+		}
+	}
+
+	if len(problems) > 0 {
+		return validationFailed(problems...)
+	}
+
+	return nil
+}
+
+func objects(v interface{}) []object {
+	list, _ := v.([]interface{})
+	objs := []object{}
+	for _, item := range list {
+		if obj, ok := item.(map[string]interface{}); ok {
+			objs = append(objs, obj)
+		}
+	}
+
+	return objs
+}
+
+func sortedKeys(domains map[string]*domain) []string {
+	names := []string{}
+	for name := range domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
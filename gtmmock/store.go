@@ -0,0 +1,303 @@
+package gtmmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// object is a GTM API object in its generic JSON form.
+type object map[string]interface{}
+
+// firstDataCenterID is the ID assigned to the first data center created in
+// a domain, mirroring the IDs handed out by the real API.
+const firstDataCenterID = 3131
+
+// domain is the state of a single GTM domain.
+type domain struct {
+	resource    object
+	dataCenters map[int]object
+	properties  map[string]object
+
+	changeID   string
+	modifiedAt time.Time
+
+	// dir is the export directory the domain is persisted to, if any.
+	dir string
+}
+
+func newDomain(resource object) *domain {
+	return &domain{
+		resource:    resource,
+		dataCenters: map[int]object{},
+		properties:  map[string]object{},
+	}
+}
+
+func (d *domain) name() string {
+	name, _ := d.resource["name"].(string)
+	return name
+}
+
+func (d *domain) nextDataCenterID() int {
+	id := firstDataCenterID
+	for existing := range d.dataCenters {
+		if existing >= id {
+			id = existing + 1
+		}
+	}
+
+	return id
+}
+
+func (d *domain) sortedDataCenters() []object {
+	ids := []int{}
+	for id := range d.dataCenters {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	dcs := []object{}
+	for _, id := range ids {
+		dcs = append(dcs, d.dataCenters[id])
+	}
+
+	return dcs
+}
+
+func (d *domain) sortedProperties() []object {
+	names := []string{}
+	for name := range d.properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	props := []object{}
+	for _, name := range names {
+		props = append(props, d.properties[name])
+	}
+
+	return props
+}
+
+// full returns the domain resource with its data centers and properties,
+// as returned by GET /domains/{domain}.
+func (d *domain) full(status object) object {
+	full := object{}
+	for k, v := range d.resource {
+		full[k] = v
+	}
+	full["datacenters"] = d.sortedDataCenters()
+	full["properties"] = d.sortedProperties()
+	full["status"] = status
+
+	return full
+}
+
+// loadDir reads domains from dir, which is either an export directory as
+// written by "akamai-gtm export" or a directory of export directories.
+func loadDir(dir string) ([]*domain, error) {
+	if _, ok := findObjectFile(dir, "domain"); ok {
+		d, err := loadDomainDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		return []*domain{d}, nil
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	domains := []*domain{}
+	for _, info := range infos {
+		sub := filepath.Join(dir, info.Name())
+		if _, ok := findObjectFile(sub, "domain"); !info.IsDir() || !ok {
+			continue
+		}
+		d, err := loadDomainDir(sub)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, d)
+	}
+
+	return domains, nil
+}
+
+func loadDomainDir(dir string) (*domain, error) {
+	path, _ := findObjectFile(dir, "domain")
+	resource, err := readObject(path)
+	if err != nil {
+		return nil, err
+	}
+	delete(resource, "datacenters")
+	delete(resource, "properties")
+	delete(resource, "status")
+	d := newDomain(resource)
+	d.dir = dir
+
+	for _, path := range objectFiles(filepath.Join(dir, "datacenters")) {
+		dc, err := readObject(path)
+		if err != nil {
+			return nil, err
+		}
+		id, ok := intField(dc, "datacenterId")
+		if !ok {
+			return nil, fmt.Errorf("%s: no datacenterId", path)
+		}
+		d.dataCenters[id] = dc
+	}
+
+	for _, path := range objectFiles(filepath.Join(dir, "properties")) {
+		prop, err := readObject(path)
+		if err != nil {
+			return nil, err
+		}
+		name, _ := prop["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("%s: no name", path)
+		}
+		d.properties[name] = prop
+	}
+
+	return d, nil
+}
+
+func findObjectFile(dir, name string) (string, bool) {
+	for _, ext := range []string{".json", ".yaml", ".yml"} {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+func objectFiles(dir string) []string {
+	infos, _ := ioutil.ReadDir(dir)
+	files := []string{}
+	for _, info := range infos {
+		switch strings.ToLower(filepath.Ext(info.Name())) {
+		case ".json", ".yaml", ".yml":
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+
+	return files
+}
+
+func readObject(path string) (object, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		// round trip through JSON to turn YAML maps into JSON objects
+		if data, err = json.Marshal(jsonCompatible(raw)); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	obj := object{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return obj, nil
+}
+
+func jsonCompatible(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range t {
+			m[fmt.Sprint(k)] = jsonCompatible(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range t {
+			t[i] = jsonCompatible(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// save writes the domain to its export directory as JSON, removing the
+// files of deleted objects.
+func (d *domain) save() error {
+	if d.dir == "" {
+		return nil
+	}
+
+	for _, sub := range []string{"datacenters", "properties"} {
+		if err := os.MkdirAll(filepath.Join(d.dir, sub), 0755); err != nil {
+			return err
+		}
+		for _, path := range objectFiles(filepath.Join(d.dir, sub)) {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if path, ok := findObjectFile(d.dir, "domain"); ok {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	if err := writeObject(filepath.Join(d.dir, "domain.json"), d.resource); err != nil {
+		return err
+	}
+	for id, dc := range d.dataCenters {
+		if err := writeObject(filepath.Join(d.dir, "datacenters", strconv.Itoa(id)+".json"), dc); err != nil {
+			return err
+		}
+	}
+	for name, prop := range d.properties {
+		if err := writeObject(filepath.Join(d.dir, "properties", name+".json"), prop); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeObject(path string, obj object) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func intField(obj object, key string) (int, bool) {
+	switch v := obj[key].(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	case json.Number:
+		i, err := strconv.Atoi(v.String())
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
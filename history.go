@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/comcast/akamai-gtm/history"
+	"github.com/comcast/akamai-gtm/hooks"
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// historyStore is the local history changes are recorded in, and
+// historyComment the --comment recorded with them.
+var (
+	historyStore   *history.Store
+	historyComment string
+)
+
+// openHistory sets up the local history before any command runs.
+func openHistory(c *cli.Context) error {
+	historyStore = &history.Store{Dir: filepath.Join(stateDir(c), "history")}
+	historyComment = c.GlobalString("comment")
+
+	return nil
+}
+
+// record adds a change made through h to the local history. Failing to
+// record it only warns, as the change has been made.
+func (h *hooked) record(operation, domain, objectType, object string, before, after interface{}) {
+	if h.history == nil {
+		return
+	}
+	// data centers are created by nickname but known by ID
+	if dc, ok := after.(*edgegrid.DataCenter); ok && dc.DataCenterID != 0 {
+		object = strconv.Itoa(dc.DataCenterID)
+	}
+	v := history.Version{
+		Time:       time.Now().UTC(),
+		User:       hooks.CurrentUser(),
+		Command:    h.command,
+		Operation:  operation,
+		ObjectType: objectType,
+		Object:     object,
+		Comment:    h.comment,
+	}
+	var err error
+	if v.Before, err = history.Encode(before); err == nil {
+		if v.After, err = history.Encode(after); err == nil {
+			_, err = h.history.Record(domain, v)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record %s in the history: %v\n", config.ObjectID(objectType, object), err)
+	}
+}
+
+func historyList(c *cli.Context) error {
+	domain := c.Args().First()
+	if domain == "" {
+		return fmt.Errorf("a Domain name is required")
+	}
+	versions, err := historyStore.Versions(domain)
+	if err != nil {
+		return err
+	}
+
+	object := c.String("object")
+	rows := [][]string{}
+	for _, v := range versions {
+		id := config.ObjectID(v.ObjectType, v.Object)
+		if object != "" && id != object {
+			continue
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(v.ID),
+			v.Time.Local().Format("2006-01-02 15:04:05"),
+			v.User,
+			v.Command,
+			v.Operation + " " + id,
+			v.Comment,
+		})
+	}
+	if len(rows) == 0 {
+		fmt.Printf("No history found for domain: %s\n", domain)
+		return nil
+	}
+	render.TableWithHeaders(os.Stdout, []string{"Version", "Time", "User", "Command", "Change", "Comment"}, rows)
+
+	return nil
+}
+
+func historyDiff(c *cli.Context) error {
+	args := c.Args()
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("usage: %s", c.Command.Usage)
+	}
+	domain := args[0]
+	from, err := history.ParseRef(args[1])
+	if err != nil {
+		return err
+	}
+	// a single version is compared with the state before it
+	to := from
+	if len(args) == 3 {
+		if to, err = history.ParseRef(args[2]); err != nil {
+			return err
+		}
+	} else {
+		from.Before = true
+	}
+
+	fromVersion, fromState, err := historyStore.Lookup(domain, from)
+	if err != nil {
+		return err
+	}
+	toVersion, toState, err := historyStore.Lookup(domain, to)
+	if err != nil {
+		return err
+	}
+	a, err := indentState(fromState)
+	if err != nil {
+		return err
+	}
+	b, err := indentState(toState)
+	if err != nil {
+		return err
+	}
+
+	diff := unifiedDiff(historyName(fromVersion, from), historyName(toVersion, to), a, b)
+	if diff == "" {
+		fmt.Println("No differences")
+		return nil
+	}
+	fmt.Print(diff)
+
+	return nil
+}
+
+// historyName names the state of an object in a diff, e.g.
+// property:www@3.
+func historyName(v *history.Version, ref history.Ref) string {
+	return config.ObjectID(v.ObjectType, v.Object) + "@" + ref.String()
+}
+
+// indentState indents an object's JSON for a diff; an object that did not
+// exist is empty.
+func indentState(state json.RawMessage) (string, error) {
+	if len(state) == 0 {
+		return "", nil
+	}
+	out := &bytes.Buffer{}
+	if err := json.Indent(out, state, "", "  "); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+func rollback(c *cli.Context) error {
+	domain := c.Args().First()
+	if domain == "" {
+		return fmt.Errorf("a Domain name is required")
+	}
+	if c.String("to") == "" {
+		return fmt.Errorf("--to is required")
+	}
+	ref, err := history.ParseRef(c.String("to"))
+	if err != nil {
+		return err
+	}
+	v, state, err := historyStore.Lookup(domain, ref)
+	if err != nil {
+		return err
+	}
+	if historyComment == "" {
+		historyComment = "rollback to version " + ref.String()
+	}
+	api := client(c)
+	id := config.ObjectID(v.ObjectType, v.Object)
+
+	var live, target interface{}
+	var submit func() error
+	switch v.ObjectType {
+	case "property":
+		doc, err := api.DomainDocument(domain)
+		if err != nil {
+			return err
+		}
+		if inDomain(doc, "properties", "name", v.Object) {
+			if live, err = api.Property(domain, v.Object); err != nil {
+				return err
+			}
+		}
+		if state == nil {
+			submit = func() error {
+				_, err := api.PropertyDelete(domain, v.Object)
+				return err
+			}
+			break
+		}
+		prop := &edgegrid.Property{}
+		if err := json.Unmarshal(state, prop); err != nil {
+			return err
+		}
+		target = prop
+		submit = func() error {
+			_, err := api.PropertyUpdate(domain, prop)
+			return err
+		}
+	case "datacenter":
+		dcID, err := strconv.Atoi(v.Object)
+		if err != nil {
+			return fmt.Errorf("version %s of %s has no data center ID", ref, id)
+		}
+		doc, err := api.DomainDocument(domain)
+		if err != nil {
+			return err
+		}
+		if inDomain(doc, "datacenters", "datacenterId", v.Object) {
+			if live, err = api.DataCenter(domain, dcID); err != nil {
+				return err
+			}
+		}
+		if state == nil {
+			submit = func() error {
+				return api.DataCenterDelete(domain, dcID)
+			}
+			break
+		}
+		dc := &edgegrid.DataCenter{}
+		if err := json.Unmarshal(state, dc); err != nil {
+			return err
+		}
+		target = dc
+		submit = func() error {
+			if live == nil {
+				resp, err := api.DataCenterCreate(domain, dc)
+				if err == nil {
+					fmt.Printf("%s no longer exists; recreated it as data center %d\n", id, resp.DataCenter.DataCenterID)
+				}
+				return err
+			}
+			_, err := api.DataCenterUpdate(domain, dc)
+			return err
+		}
+	case "domain":
+		if state == nil {
+			return fmt.Errorf("%s did not exist at version %s; use domain-delete to delete it", id, ref)
+		}
+		dom := &edgegrid.Domain{}
+		if err := json.Unmarshal(state, dom); err != nil {
+			return err
+		}
+		live, err = api.Domain(domain)
+		if err != nil {
+			return err
+		}
+		target = dom
+		submit = func() error {
+			_, err := api.DomainUpdate(dom)
+			return err
+		}
+	default:
+		return fmt.Errorf("rolling back %s objects is not supported", v.ObjectType)
+	}
+
+	if live == nil && target == nil {
+		fmt.Printf("%s does not exist, as at version %s\n", id, ref)
+		return nil
+	}
+	diff, err := objectDiff(id, live, target)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Printf("%s is already as at version %s\n", id, ref)
+		return nil
+	}
+	fmt.Print(diff)
+
+	if !c.Bool("yes") {
+		answer, err := prompt(fmt.Sprintf("Roll %s back to version %s? [y/n] ", id, ref), "--yes")
+		if err != nil {
+			return err
+		}
+		if answer = strings.ToLower(answer); answer != "y" && answer != "yes" {
+			return fmt.Errorf("%s not rolled back", id)
+		}
+	}
+	if err := submit(); err != nil {
+		return err
+	}
+	fmt.Printf("Rolled %s back to version %s\n", id, ref)
+
+	return nil
+}
+
+// inDomain reports whether the raw document of a domain lists an object
+// under key, e.g. a property by name or a data center by ID.
+func inDomain(doc map[string]interface{}, key, field, value string) bool {
+	items, _ := doc[key].([]interface{})
+	for _, item := range items {
+		obj, _ := item.(map[string]interface{})
+		got := fmt.Sprint(obj[field])
+		if id, ok := obj[field].(float64); ok {
+			got = strconv.FormatFloat(id, 'f', -1, 64)
+		}
+		if got == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,191 @@
+// Package history keeps a local record of the changes made to GTM objects,
+// with each object's JSON before and after the change, so that they can be
+// reviewed and rolled back.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is a change to an object and the version of the object it made.
+// Versions are numbered from 1 within a domain.
+type Version struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	User string    `json:"user"`
+	// Command is the akamai-gtm command that made the change.
+	Command string `json:"command"`
+	// Operation is create, update or delete.
+	Operation  string `json:"operation"`
+	ObjectType string `json:"objectType"`
+	Object     string `json:"object"`
+	Comment    string `json:"comment,omitempty"`
+	// Before and After are the object's JSON before and after the change,
+	// absent for the object a create or delete did not have.
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Store is a history kept in a directory, as a file of JSON lines per
+// domain.
+type Store struct {
+	Dir string
+}
+
+// Encode returns the JSON of an object for Version.Before or After, or nil
+// for a nil object.
+func Encode(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
+
+// lockTimeout is how long Record waits for another command recording a
+// change to the same domain.
+const lockTimeout = 10 * time.Second
+
+func (s *Store) path(domain string) string {
+	return filepath.Join(s.Dir, url.PathEscape(domain)+".jsonl")
+}
+
+// lock takes the lock on the history of a domain, a file created
+// exclusively next to it, and returns a function releasing it.
+func (s *Store) lock(domain string) (func(), error) {
+	path := s.path(domain) + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("history of %s is locked by %s; remove it if no other command is running", domain, path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Record adds a version to the history of a domain, numbering it, and
+// returns its number. Concurrent records are serialized by a lock file.
+func (s *Store) Record(domain string, v Version) (int, error) {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return 0, err
+	}
+	unlock, err := s.lock(domain)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	versions, err := s.Versions(domain)
+	if err != nil {
+		return 0, err
+	}
+	v.ID = len(versions) + 1
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(s.path(domain), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return 0, err
+	}
+
+	return v.ID, f.Close()
+}
+
+// Versions returns the history of a domain, oldest first.
+func (s *Store) Versions(domain string) ([]Version, error) {
+	f, err := os.Open(s.path(domain))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	versions := []Version{}
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			v := Version{}
+			if err := json.Unmarshal(data, &v); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", s.path(domain), line, err)
+			}
+			versions = append(versions, v)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	return versions, nil
+}
+
+// Ref names the state of an object in the history: N is the object as
+// version N left it, and N^ as it was before version N.
+type Ref struct {
+	ID     int
+	Before bool
+}
+
+// ParseRef parses a Ref.
+func ParseRef(s string) (Ref, error) {
+	ref := Ref{Before: strings.HasSuffix(s, "^")}
+	id, err := strconv.Atoi(strings.TrimSuffix(s, "^"))
+	if err != nil || id < 1 {
+		return ref, fmt.Errorf("invalid version %q: use a number from history, or <number>^ for the state before it", s)
+	}
+	ref.ID = id
+
+	return ref, nil
+}
+
+func (r Ref) String() string {
+	if r.Before {
+		return strconv.Itoa(r.ID) + "^"
+	}
+
+	return strconv.Itoa(r.ID)
+}
+
+// Lookup returns the version a Ref names in the history of a domain and
+// the object's JSON in the state it names, nil if the object did not
+// exist.
+func (s *Store) Lookup(domain string, ref Ref) (*Version, json.RawMessage, error) {
+	versions, err := s.Versions(domain)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ref.ID > len(versions) {
+		return nil, nil, fmt.Errorf("%s has no version %d; see history", domain, ref.ID)
+	}
+	v := &versions[ref.ID-1]
+	if ref.Before {
+		return v, v.Before, nil
+	}
+
+	return v, v.After, nil
+}
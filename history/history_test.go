@@ -0,0 +1,110 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "akamai-gtm-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Store{Dir: dir}, func() { os.RemoveAll(dir) }
+}
+
+func TestRecordConcurrently(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	const writers, records = 10, 10
+	const n = writers * records
+	ids := make([]int, n)
+	errs := make([]error, n)
+	wg := sync.WaitGroup{}
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w * records; i < (w+1)*records; i++ {
+				ids[i], errs[i] = s.Record("example.akadns.net", Version{Operation: "update", ObjectType: "property", Object: "www"})
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Ints(ids)
+	for i, id := range ids {
+		if id != i+1 {
+			t.Fatalf("ids = %v, want 1 to %d", ids, n)
+		}
+	}
+	versions, err := s.Versions("example.akadns.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range versions {
+		if v.ID != i+1 {
+			t.Errorf("version %d has id %d", i+1, v.ID)
+		}
+	}
+	if len(versions) != n {
+		t.Errorf("%d versions, want %d", len(versions), n)
+	}
+}
+
+func TestRecordEscapesDomain(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	domain := "../escaped/example.akadns.net"
+	if _, err := s.Record(domain, Version{Operation: "create"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, "..%2Fescaped%2Fexample.akadns.net.jsonl")); err != nil {
+		t.Error(err)
+	}
+	versions, err := s.Versions(domain)
+	if err != nil || len(versions) != 1 {
+		t.Errorf("versions = %v, %v, want one", versions, err)
+	}
+}
+
+func TestRecordWaitsForLock(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	lock := s.path("example.akadns.net") + ".lock"
+	if err := ioutil.WriteFile(lock, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := s.Record("example.akadns.net", Version{Operation: "create"})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Record returned %v while the history was locked", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	os.Remove(lock)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lock); !os.IsNotExist(err) {
+		t.Errorf("lock file was not removed: %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/akamai-gtm/history"
+	"github.com/comcast/akamai-gtm/hooks"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// hookConfig is the hooks configuration loaded from --hooks, if any.
+var hookConfig *hooks.Config
+
+// propagationPollInterval is how often the domain status is checked while
+// waiting for a change to propagate.
+const propagationPollInterval = 10 * time.Second
+
+// loadHooks loads the --hooks configuration before any command runs.
+func loadHooks(c *cli.Context) error {
+	path := c.GlobalString("hooks")
+	if path == "" {
+		return nil
+	}
+	cfg, err := hooks.Load(path)
+	if err != nil {
+		return err
+	}
+	hookConfig = cfg
+
+	return nil
+}
+
+// hooked is a gtm.API that runs the configured hooks around each change
+// made through it and records the change in the local history.
+type hooked struct {
+	gtm.API
+	hooks   *hooks.Config
+	history *history.Store
+	comment string
+	command string
+}
+
+// withHooks wraps api to run the configured hooks, if there are any, and
+// record the changes made by command in the history, if it is kept.
+func withHooks(api gtm.API, command string) gtm.API {
+	cfg := hookConfig
+	if cfg == nil {
+		cfg = &hooks.Config{}
+	}
+	if len(cfg.Pre)+len(cfg.Post) == 0 && historyStore == nil {
+		return api
+	}
+
+	return &hooked{API: api, hooks: cfg, history: historyStore, comment: historyComment, command: command}
+}
+
+// change runs the pre hooks, submits a change unless one of them failed,
+// records it and then runs the post hooks. before and after are the object
+// before and after the change, nil for a create or delete.
+func (h *hooked) change(operation, domain, objectType, object string, before, after interface{}, submit func() (*edgegrid.DomainStatus, error)) error {
+	diff, err := objectDiff(config.ObjectID(objectType, object), before, after)
+	if err != nil {
+		return err
+	}
+	event := hooks.Event{
+		Operation:  operation,
+		Command:    h.command,
+		Domain:     domain,
+		ObjectType: objectType,
+		Object:     object,
+		Diff:       diff,
+		User:       hooks.CurrentUser(),
+		Time:       time.Now().UTC(),
+	}
+	if err := h.hooks.RunPre(event); err != nil {
+		return fmt.Errorf("%s not %sd: %v", config.ObjectID(objectType, object), operation, err)
+	}
+
+	status, err := submit()
+	if err != nil {
+		return err
+	}
+	h.record(operation, domain, objectType, object, before, after)
+	if len(h.hooks.Post) == 0 {
+		return nil
+	}
+
+	event.Status = h.propagationStatus(domain, status)
+	event.Time = time.Now().UTC()
+	for _, err := range h.hooks.RunPost(event) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	return nil
+}
+
+// propagationStatus returns the domain's status after a change, waiting
+// for the change to propagate if the hooks configuration asks to.
+func (h *hooked) propagationStatus(domain string, status *edgegrid.DomainStatus) *edgegrid.DomainStatus {
+	deadline := time.Now().Add(h.hooks.WaitDuration())
+	for {
+		if status != nil && (status.PropagationStatus != "PENDING" || !time.Now().Before(deadline)) {
+			return status
+		}
+		if status != nil {
+			time.Sleep(propagationPollInterval)
+		}
+		current, err := h.API.DomainStatus(domain)
+		if err != nil {
+			return status
+		}
+		status = current
+	}
+}
+
+// objectDiff returns a unified diff of the JSON of an object before and
+// after a change, either of which may be nil.
+func objectDiff(id string, before, after interface{}) (string, error) {
+	text := func(v interface{}) (string, error) {
+		if v == nil {
+			return "", nil
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
+		return string(data), err
+	}
+	a, err := text(before)
+	if err != nil {
+		return "", err
+	}
+	b, err := text(after)
+	if err != nil {
+		return "", err
+	}
+
+	return unifiedDiff("before/"+id, "after/"+id, a, b), nil
+}
+
+func (h *hooked) DomainCreate(name, domainType string) (*edgegrid.DomainResponse, error) {
+	var resp *edgegrid.DomainResponse
+	after := &edgegrid.Domain{Name: name, Type: domainType}
+	err := h.change("create", name, "domain", name, nil, after, func() (status *edgegrid.DomainStatus, err error) {
+		if resp, err = h.API.DomainCreate(name, domainType); err != nil {
+			return nil, err
+		}
+		return resp.Status, nil
+	})
+
+	return resp, err
+}
+
+func (h *hooked) DomainCreateFrom(definition interface{}, contract, group string) (*edgegrid.DomainResponse, error) {
+	name := ""
+	if doc, ok := definition.(map[string]interface{}); ok {
+		name, _ = doc["name"].(string)
+	}
+
+	var resp *edgegrid.DomainResponse
+	err := h.change("create", name, "domain", name, nil, definition, func() (status *edgegrid.DomainStatus, err error) {
+		if resp, err = h.API.DomainCreateFrom(definition, contract, group); err != nil {
+			return nil, err
+		}
+		return resp.Status, nil
+	})
+
+	return resp, err
+}
+
+func (h *hooked) DomainUpdate(domain *edgegrid.Domain) (*edgegrid.DomainResponse, error) {
+	var before interface{}
+	if live, err := h.API.Domain(domain.Name); err == nil {
+		before = live
+	}
+
+	var resp *edgegrid.DomainResponse
+	err := h.change("update", domain.Name, "domain", domain.Name, before, domain, func() (status *edgegrid.DomainStatus, err error) {
+		if resp, err = h.API.DomainUpdate(domain); err != nil {
+			return nil, err
+		}
+		return resp.Status, nil
+	})
+
+	return resp, err
+}
+
+func (h *hooked) DomainDelete(name string) (*edgegrid.DomainStatus, error) {
+	var before interface{}
+	if live, err := h.API.DomainDocument(name); err == nil {
+		before = live
+	}
+
+	var status *edgegrid.DomainStatus
+	err := h.change("delete", name, "domain", name, before, nil, func() (_ *edgegrid.DomainStatus, err error) {
+		status, err = h.API.DomainDelete(name)
+		return status, err
+	})
+
+	return status, err
+}
+
+func (h *hooked) ObjectDelete(domain, collection, name string) (*edgegrid.DomainStatus, error) {
+	objectType := collection
+	for _, kind := range domainContents {
+		if kind.collection == collection {
+			objectType = kind.objectType
+		}
+	}
+	var before interface{}
+	if live, err := h.API.DomainDocument(domain); err == nil {
+		objects, _ := live[gtm.CollectionKey(collection)].([]interface{})
+		for _, obj := range objects {
+			if o, _ := obj.(map[string]interface{}); o != nil && o["name"] == name {
+				before = o
+			}
+		}
+	}
+
+	var status *edgegrid.DomainStatus
+	err := h.change("delete", domain, objectType, name, before, nil, func() (_ *edgegrid.DomainStatus, err error) {
+		status, err = h.API.ObjectDelete(domain, collection, name)
+		return status, err
+	})
+
+	return status, err
+}
+
+func (h *hooked) DataCenterCreate(domain string, dc *edgegrid.DataCenter) (*edgegrid.DataCenterResponse, error) {
+	// the created data center, with the ID it was given, is recorded
+	created := *dc
+	var resp *edgegrid.DataCenterResponse
+	err := h.change("create", domain, "datacenter", dc.Nickname, nil, &created, func() (status *edgegrid.DomainStatus, err error) {
+		if resp, err = h.API.DataCenterCreate(domain, dc); err != nil {
+			return nil, err
+		}
+		if resp.DataCenter != nil {
+			created = *resp.DataCenter
+		}
+		return resp.Status, nil
+	})
+
+	return resp, err
+}
+
+func (h *hooked) DataCenterUpdate(domain string, dc *edgegrid.DataCenter) (*edgegrid.DataCenterResponse, error) {
+	var before interface{}
+	if live, err := h.API.DataCenter(domain, dc.DataCenterID); err == nil {
+		before = live
+	}
+
+	var resp *edgegrid.DataCenterResponse
+	err := h.change("update", domain, "datacenter", strconv.Itoa(dc.DataCenterID), before, dc, func() (status *edgegrid.DomainStatus, err error) {
+		if resp, err = h.API.DataCenterUpdate(domain, dc); err != nil {
+			return nil, err
+		}
+		return resp.Status, nil
+	})
+
+	return resp, err
+}
+
+func (h *hooked) DataCenterDelete(domain string, id int) error {
+	var before interface{}
+	if live, err := h.API.DataCenter(domain, id); err == nil {
+		before = live
+	}
+
+	return h.change("delete", domain, "datacenter", strconv.Itoa(id), before, nil, func() (*edgegrid.DomainStatus, error) {
+		return nil, h.API.DataCenterDelete(domain, id)
+	})
+}
+
+func (h *hooked) PropertyCreate(domain string, prop *edgegrid.Property) (*edgegrid.PropertyResponse, error) {
+	var resp *edgegrid.PropertyResponse
+	err := h.change("create", domain, "property", prop.Name, nil, prop, func() (status *edgegrid.DomainStatus, err error) {
+		if resp, err = h.API.PropertyCreate(domain, prop); err != nil {
+			return nil, err
+		}
+		return resp.Status, nil
+	})
+
+	return resp, err
+}
+
+func (h *hooked) PropertyUpdate(domain string, prop *edgegrid.Property) (*edgegrid.PropertyResponse, error) {
+	var before interface{}
+	if live, err := h.API.Property(domain, prop.Name); err == nil {
+		before = live
+	}
+
+	var resp *edgegrid.PropertyResponse
+	err := h.change("update", domain, "property", prop.Name, before, prop, func() (status *edgegrid.DomainStatus, err error) {
+		if resp, err = h.API.PropertyUpdate(domain, prop); err != nil {
+			return nil, err
+		}
+		return resp.Status, nil
+	})
+
+	return resp, err
+}
+
+func (h *hooked) PropertyDelete(domain, name string) (bool, error) {
+	var before interface{}
+	if live, err := h.API.Property(domain, name); err == nil {
+		before = live
+	}
+
+	deleted := false
+	err := h.change("delete", domain, "property", name, before, nil, func() (status *edgegrid.DomainStatus, err error) {
+		deleted, err = h.API.PropertyDelete(domain, name)
+		return nil, err
+	})
+
+	return deleted, err
+}
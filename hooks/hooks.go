@@ -0,0 +1,222 @@
+// Package hooks runs the external commands and webhooks configured to be
+// notified before and after a change to GTM configuration.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// DefaultTimeout is how long a hook may run when it sets no timeout.
+const DefaultTimeout = 30 * time.Second
+
+// Config is a hooks configuration file.
+type Config struct {
+	// Pre hooks run before a change is submitted; if one fails the change
+	// is not made.
+	Pre []Hook `json:"pre"`
+	// Post hooks run after a change was accepted.
+	Post []Hook `json:"post"`
+	// Wait is how long to wait for a change to propagate before running
+	// the post hooks, e.g. 10m, so their event carries the final
+	// propagation status. By default they run as soon as the change is
+	// accepted.
+	Wait string `json:"wait"`
+
+	wait time.Duration
+}
+
+// Hook is an external command, run with sh -c and given the event as JSON
+// on stdin, or a URL the event is POSTed to as JSON.
+type Hook struct {
+	Command string            `json:"command"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Timeout string            `json:"timeout"`
+
+	timeout time.Duration
+}
+
+// Event describes a change to a GTM object.
+type Event struct {
+	// Phase is pre or post.
+	Phase string `json:"phase"`
+	// Operation is create, update or delete.
+	Operation string `json:"operation"`
+	// Command is the akamai-gtm command making the change.
+	Command    string `json:"command"`
+	Domain     string `json:"domain"`
+	ObjectType string `json:"objectType"`
+	Object     string `json:"object"`
+	// Diff is a unified diff of the object's JSON before and after the
+	// change.
+	Diff string    `json:"diff"`
+	User string    `json:"user"`
+	Time time.Time `json:"time"`
+	// Status is the domain's propagation status after the change; it is
+	// only set for post hooks.
+	Status *edgegrid.DomainStatus `json:"status,omitempty"`
+}
+
+// Load reads a hooks configuration file in YAML or JSON.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	if err := config.UnmarshalFile(path, cfg); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if cfg.Wait != "" {
+		if cfg.wait, err = time.ParseDuration(cfg.Wait); err != nil {
+			return nil, fmt.Errorf("%s: invalid wait %q: %v", path, cfg.Wait, err)
+		}
+	}
+	for _, hooks := range [][]Hook{cfg.Pre, cfg.Post} {
+		for i := range hooks {
+			h := &hooks[i]
+			if (h.Command == "") == (h.URL == "") {
+				return nil, fmt.Errorf("%s: each hook needs one of command or url", path)
+			}
+			h.timeout = DefaultTimeout
+			if h.Timeout != "" {
+				if h.timeout, err = time.ParseDuration(h.Timeout); err != nil {
+					return nil, fmt.Errorf("%s: invalid timeout %q: %v", path, h.Timeout, err)
+				}
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// WaitDuration returns how long to wait for propagation before running the
+// post hooks.
+func (cfg *Config) WaitDuration() time.Duration {
+	return cfg.wait
+}
+
+// RunPre runs the pre hooks in order, stopping at the first that fails.
+func (cfg *Config) RunPre(event Event) error {
+	event.Phase = "pre"
+	for _, h := range cfg.Pre {
+		if err := h.Run(event); err != nil {
+			return fmt.Errorf("pre hook %s failed: %v", h, err)
+		}
+	}
+
+	return nil
+}
+
+// RunPost runs every post hook and returns the errors of those that
+// failed.
+func (cfg *Config) RunPost(event Event) []error {
+	event.Phase = "post"
+	errs := []error{}
+	for _, h := range cfg.Post {
+		if err := h.Run(event); err != nil {
+			errs = append(errs, fmt.Errorf("post hook %s failed: %v", h, err))
+		}
+	}
+
+	return errs
+}
+
+func (h Hook) String() string {
+	if h.URL != "" {
+		return h.URL
+	}
+
+	return fmt.Sprintf("%q", h.Command)
+}
+
+// Run sends event to the hook.
+func (h Hook) Run(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	timeout := h.timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if h.URL != "" {
+		return h.post(ctx, body)
+	}
+
+	return h.exec(ctx, body)
+}
+
+func (h Hook) exec(ctx context.Context, body []byte) error {
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%v: %s", err, lastLine(msg))
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (h Hook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(s, "\n")
+
+	return lines[len(lines)-1]
+}
+
+// CurrentUser returns the user to record in events: $AKAMAI_GTM_USER if
+// set, e.g. by a CI job acting for someone, or the local user.
+func CurrentUser() string {
+	if name := os.Getenv("AKAMAI_GTM_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
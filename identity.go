@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/urfave/cli"
+)
+
+func contracts(c *cli.Context) error {
+	contracts, err := client(c).Contracts()
+	if err != nil {
+		return err
+	}
+	if len(contracts) == 0 {
+		fmt.Println("No contracts found")
+		return nil
+	}
+
+	data := [][]string{}
+	for _, contract := range contracts {
+		data = append(data, []string{contract.ContractID, contract.ContractName})
+	}
+	render.TableWithHeaders(os.Stdout, []string{"Contract ID", "Name"}, data)
+
+	return nil
+}
+
+func groups(c *cli.Context) error {
+	groups, err := client(c).Groups()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, group := range groups {
+		if contract := c.String("contract"); contract != "" {
+			inContract := false
+			for _, id := range group.ContractIDs {
+				inContract = inContract || id == contract
+			}
+			if !inContract {
+				continue
+			}
+		}
+		data = append(data, []string{strconv.Itoa(group.GroupID), group.GroupName, strings.Join(group.ContractIDs, ", ")})
+	}
+	if len(data) == 0 {
+		fmt.Println("No groups found")
+		return nil
+	}
+	render.TableWithHeaders(os.Stdout, []string{"Group ID", "Name", "Contracts"}, data)
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// importColumns are the DataCenter fields data-centers-import sets. Column
+// headers match them, or their JSON names, case-insensitively.
+var importColumns = []string{
+	"Nickname", "City", "Country", "Continent", "StateOrProvince",
+	"Latitude", "Longitude", "Virtual", "CloudServerTargeting",
+}
+
+// importRow is a row of a data center import and what it does. Lines are
+// numbered as in a spreadsheet, with the header on line 1.
+type importRow struct {
+	line     int
+	nickname string
+	action   string // create, update, unchanged or invalid
+	changes  []string
+	err      error
+	dc       *edgegrid.DataCenter
+}
+
+func dataCentersImport(c *cli.Context) error {
+	domain := c.Args().First()
+	path := c.String("csv")
+	if path == "" {
+		return fmt.Errorf("--csv is required")
+	}
+	data, err := config.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	api := client(c)
+	existing, err := api.DataCenters(domain)
+	if err != nil {
+		return err
+	}
+
+	rows, err := planImport(data, existing)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	preview := [][]string{}
+	invalid := 0
+	for _, row := range rows {
+		detail := strings.Join(row.changes, "; ")
+		if row.err != nil {
+			detail = row.err.Error()
+			invalid++
+		}
+		preview = append(preview, []string{strconv.Itoa(row.line), row.nickname, row.action, detail})
+	}
+	render.TableWithHeaders(os.Stdout, []string{"Line", "Nickname", "Action", "Changes"}, preview)
+
+	if invalid > 0 {
+		return fmt.Errorf("%d invalid rows; nothing was imported", invalid)
+	}
+	if c.Bool("dry-run") {
+		return nil
+	}
+	if !c.Bool("yes") {
+		answer, err := prompt("Import these rows? [y/n] ", "--yes")
+		if err != nil {
+			return err
+		}
+		if answer = strings.ToLower(answer); answer != "y" && answer != "yes" {
+			return fmt.Errorf("nothing was imported")
+		}
+	}
+
+	results := [][]string{}
+	failed := 0
+	for _, row := range rows {
+		result := "unchanged"
+		switch row.action {
+		case "create":
+			resp, err := api.DataCenterCreate(domain, row.dc)
+			switch {
+			case err != nil:
+				result = "failed: " + err.Error()
+				failed++
+			case resp.DataCenter != nil:
+				result = fmt.Sprintf("created %d", resp.DataCenter.DataCenterID)
+			default:
+				result = "created"
+			}
+		case "update":
+			if _, err := api.DataCenterUpdate(domain, row.dc); err != nil {
+				result = "failed: " + err.Error()
+				failed++
+			} else {
+				result = "updated"
+			}
+		}
+		results = append(results, []string{strconv.Itoa(row.line), row.nickname, result})
+	}
+	fmt.Println()
+	render.TableWithHeaders(os.Stdout, []string{"Line", "Nickname", "Result"}, results)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", failed, len(rows))
+	}
+
+	return nil
+}
+
+// planImport parses CSV data with a header row and works out, for each
+// row, whether it creates a data center or updates the existing one with
+// the same nickname. Empty cells leave existing values unchanged.
+func planImport(data []byte, existing []edgegrid.DataCenter) ([]importRow, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("no header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	fields, err := importFields(header)
+	if err != nil {
+		return nil, err
+	}
+	nicknameColumn := -1
+	for i, field := range fields {
+		if field.Name == "Nickname" {
+			nicknameColumn = i
+		}
+	}
+	if nicknameColumn < 0 {
+		return nil, fmt.Errorf("no Nickname column")
+	}
+
+	byNickname := map[string][]edgegrid.DataCenter{}
+	for _, dc := range existing {
+		byNickname[dc.Nickname] = append(byNickname[dc.Nickname], dc)
+	}
+
+	rows := []importRow{}
+	seen := map[string]int{}
+	line := 1
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		row := importRow{line: line, nickname: strings.TrimSpace(record[nicknameColumn])}
+		rows = append(rows, row)
+		planImportRow(&rows[len(rows)-1], record, fields, byNickname, seen)
+	}
+
+	return rows, nil
+}
+
+func planImportRow(row *importRow, record []string, fields []reflect.StructField, byNickname map[string][]edgegrid.DataCenter, seen map[string]int) {
+	row.action = "invalid"
+	switch {
+	case row.nickname == "":
+		row.err = fmt.Errorf("no nickname")
+		return
+	case seen[row.nickname] != 0:
+		row.err = fmt.Errorf("%s is also on line %d", row.nickname, seen[row.nickname])
+		return
+	case len(byNickname[row.nickname]) > 1:
+		row.err = fmt.Errorf("the domain has %d data centers named %s", len(byNickname[row.nickname]), row.nickname)
+		return
+	}
+	seen[row.nickname] = row.line
+
+	dc := &edgegrid.DataCenter{Nickname: row.nickname}
+	row.action = "create"
+	if matches := byNickname[row.nickname]; len(matches) == 1 {
+		*dc = matches[0]
+		row.action = "update"
+	}
+
+	v := reflect.ValueOf(dc).Elem()
+	for i, field := range fields {
+		cell := strings.TrimSpace(record[i])
+		if cell == "" || field.Name == "Nickname" || field.Name == "" {
+			continue
+		}
+		f := v.FieldByIndex(field.Index)
+		old := importValue(f)
+		if err := setImportField(f, cell); err != nil {
+			row.action = "invalid"
+			row.err = fmt.Errorf("%s: %v", field.Name, err)
+			return
+		}
+		if now := importValue(f); now != old {
+			if row.action == "create" {
+				row.changes = append(row.changes, fmt.Sprintf("%s: %s", field.Name, now))
+			} else {
+				row.changes = append(row.changes, fmt.Sprintf("%s: %s -> %s", field.Name, old, now))
+			}
+		}
+	}
+	if row.action == "update" && len(row.changes) == 0 {
+		row.action = "unchanged"
+	}
+	row.dc = dc
+}
+
+// importFields maps a CSV header to DataCenter fields. Columns for the
+// other DataCenter fields, such as the DataCenterID written by
+// data-centers --csv, are read-only: they map to the zero StructField,
+// which planImportRow skips.
+func importFields(header []string) ([]reflect.StructField, error) {
+	t := reflect.TypeOf(edgegrid.DataCenter{})
+	fields := []reflect.StructField{}
+	for _, name := range header {
+		name = strings.TrimSpace(name)
+		field, found := reflect.StructField{}, false
+		for i := 0; i < t.NumField() && !found; i++ {
+			field = t.Field(i)
+			jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+			found = strings.EqualFold(name, field.Name) || strings.EqualFold(name, jsonName)
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q; available columns are: %s", name, strings.Join(importColumns, ", "))
+		}
+		if !importable(field.Name) {
+			field = reflect.StructField{}
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func importable(name string) bool {
+	for _, col := range importColumns {
+		if col == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// importValue formats a field for the preview, quoting strings so that
+// empty values show.
+func importValue(f reflect.Value) string {
+	if f.Kind() == reflect.String {
+		return strconv.Quote(f.String())
+	}
+
+	return fmt.Sprint(f.Interface())
+}
+
+func setImportField(f reflect.Value, value string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		f.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		f.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported column")
+	}
+
+	return nil
+}
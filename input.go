@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/urfave/cli"
+)
+
+// readInput reads the file named by --file (or stdin when it is "-"),
+// renders it as a template and returns it as JSON, converting from YAML
+// when necessary.
+func readInput(c *cli.Context) ([]byte, error) {
+	data, format, err := renderInput(c)
+	if err != nil {
+		return nil, err
+	}
+	if format == config.FormatJSON {
+		return data, nil
+	}
+
+	return config.YAMLToJSON(data)
+}
+
+// unmarshalInput reads the --file input into v.
+func unmarshalInput(c *cli.Context, v interface{}) error {
+	data, err := readInput(c)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", inputName(c), err)
+	}
+
+	return nil
+}
+
+func inputName(c *cli.Context) string {
+	if c.String("file") == "-" {
+		return "<stdin>"
+	}
+
+	return c.String("file")
+}
@@ -0,0 +1,572 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// lint severities, in increasing order
+var lintSeverities = []string{"info", "warning", "error"}
+
+// lintIgnorePattern matches suppression directives in a property's
+// Comments, e.g. "lint:ignore dynamic-ttl-range,property-naming".
+var lintIgnorePattern = regexp.MustCompile(`lint:ignore\s+([A-Za-z0-9_,*-]+)`)
+
+// lintFinding is a single rule violation.
+type lintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Object   string `json:"object"`
+	Message  string `json:"message"`
+	File     string `json:"file,omitempty"`
+}
+
+// lintConfig is the lint configuration file.
+type lintConfig struct {
+	Rules    map[string]lintRuleConfig `json:"rules"`
+	Custom   []customLintRule          `json:"custom"`
+	Suppress []lintSuppression         `json:"suppress"`
+}
+
+// lintRuleConfig configures a built-in rule. Min, Max and Pattern are
+// only used by the rules that document them.
+type lintRuleConfig struct {
+	Enabled  *bool    `json:"enabled"`
+	Severity string   `json:"severity"`
+	Min      *float64 `json:"min"`
+	Max      *float64 `json:"max"`
+	Pattern  string   `json:"pattern"`
+}
+
+// customLintRule reports objects of the given type ("property" or
+// "datacenter") that match all of the When expressions, which use the
+// --filter syntax of the list commands.
+type customLintRule struct {
+	ID       string   `json:"id"`
+	Object   string   `json:"object"`
+	Severity string   `json:"severity"`
+	Message  string   `json:"message"`
+	When     []string `json:"when"`
+}
+
+// lintSuppression silences findings of rules matching Rule for objects
+// matching Object; both are shell patterns.
+type lintSuppression struct {
+	Rule   string `json:"rule"`
+	Object string `json:"object"`
+	Reason string `json:"reason"`
+}
+
+// lintRule is a built-in rule.
+type lintRule struct {
+	ID          string
+	Severity    string
+	Description string
+	Check       func(exp *config.Export, cfg lintRuleConfig, report func(object, message string))
+}
+
+var lintRules = []lintRule{
+	{
+		ID:          "liveness-test-required",
+		Severity:    "error",
+		Description: "Every property has at least one liveness test",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			for _, prop := range exp.Properties {
+				if len(prop.LivenessTests) == 0 {
+					report(config.ObjectID("property", prop.Name), "has no liveness tests")
+				}
+			}
+		},
+	},
+	{
+		ID:          "dynamic-ttl-range",
+		Severity:    "warning",
+		Description: "DynamicTTL is between min (default 30) and max (default 3600) seconds",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			minTTL, maxTTL := 30.0, 3600.0
+			if cfg.Min != nil {
+				minTTL = *cfg.Min
+			}
+			if cfg.Max != nil {
+				maxTTL = *cfg.Max
+			}
+			for _, prop := range exp.Properties {
+				ttl := float64(prop.DynamicTTL)
+				if prop.DynamicTTL != 0 && (ttl < minTTL || ttl > maxTTL) {
+					report(config.ObjectID("property", prop.Name), fmt.Sprintf("DynamicTTL %d is outside %g-%g", prop.DynamicTTL, minTTL, maxTTL))
+				}
+			}
+		},
+	},
+	{
+		ID:          "enabled-target-required",
+		Severity:    "error",
+		Description: "Every property has at least one enabled traffic target",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			for _, prop := range exp.Properties {
+				enabled := false
+				for _, target := range prop.TrafficTargets {
+					enabled = enabled || target.Enabled
+				}
+				if !enabled {
+					report(config.ObjectID("property", prop.Name), fmt.Sprintf("none of its %d traffic targets is enabled", len(prop.TrafficTargets)))
+				}
+			}
+		},
+	},
+	{
+		ID:          "target-servers-required",
+		Severity:    "error",
+		Description: "Every enabled traffic target has servers or a handout CNAME",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			for _, prop := range exp.Properties {
+				for _, target := range prop.TrafficTargets {
+					if target.Enabled && len(target.Servers) == 0 && render.InterfaceToStr(target.HandoutCname) == "" {
+						report(config.ObjectID("property", prop.Name), fmt.Sprintf("enabled traffic target for data center %d has no servers", target.DataCenterID))
+					}
+				}
+			}
+		},
+	},
+	{
+		ID:          "test-timeout-below-interval",
+		Severity:    "error",
+		Description: "Every liveness test's TestTimeout is less than its TestInterval",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			for _, prop := range exp.Properties {
+				for _, test := range prop.LivenessTests {
+					if test.TestInterval > 0 && test.TestTimeout >= float64(test.TestInterval) {
+						report(config.ObjectID("property", prop.Name), fmt.Sprintf("liveness test %s has TestTimeout %g not less than TestInterval %d", test.Name, test.TestTimeout, test.TestInterval))
+					}
+				}
+			}
+		},
+	},
+	{
+		ID:          "property-naming",
+		Severity:    "warning",
+		Description: "Property names end in a product suffix (name.product), or match pattern if set",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			var re *regexp.Regexp
+			if cfg.Pattern != "" {
+				var err error
+				if re, err = regexp.Compile(cfg.Pattern); err != nil {
+					report(config.ObjectID("domain", exp.Domain.Name), fmt.Sprintf("invalid property-naming pattern: %v", err))
+					return
+				}
+			}
+			for _, prop := range exp.Properties {
+				switch {
+				case re != nil && !re.MatchString(prop.Name):
+					report(config.ObjectID("property", prop.Name), fmt.Sprintf("name does not match %s", cfg.Pattern))
+				case re == nil && !strings.Contains(prop.Name, "."):
+					report(config.ObjectID("property", prop.Name), "name has no product suffix")
+				}
+			}
+		},
+	},
+	{
+		ID:          "unknown-data-center",
+		Severity:    "error",
+		Description: "Traffic targets refer to data centers of the domain",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			if len(exp.DataCenters) == 0 {
+				return
+			}
+			known := map[int]bool{}
+			for _, dc := range exp.DataCenters {
+				known[dc.DataCenterID] = true
+			}
+			for _, prop := range exp.Properties {
+				for _, target := range prop.TrafficTargets {
+					if !known[target.DataCenterID] {
+						report(config.ObjectID("property", prop.Name), fmt.Sprintf("traffic target refers to unknown data center %d", target.DataCenterID))
+					}
+				}
+			}
+		},
+	},
+	{
+		ID:          "weighted-zero-weights",
+		Severity:    "warning",
+		Description: "Weighted properties give at least one enabled traffic target a positive weight",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			for _, prop := range exp.Properties {
+				if !strings.HasPrefix(prop.Type, "weighted-") {
+					continue
+				}
+				total := 0.0
+				for _, target := range prop.TrafficTargets {
+					if target.Enabled {
+						total += target.Weight
+					}
+				}
+				if total <= 0 {
+					report(config.ObjectID("property", prop.Name), "no enabled traffic target has a positive weight")
+				}
+			}
+		},
+	},
+	{
+		ID:          "data-center-unused",
+		Severity:    "info",
+		Description: "Every data center is used by a property",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			used := map[int]bool{}
+			for _, prop := range exp.Properties {
+				for _, target := range prop.TrafficTargets {
+					used[target.DataCenterID] = true
+				}
+			}
+			for _, dc := range exp.DataCenters {
+				if !used[dc.DataCenterID] {
+					report(config.ObjectID("datacenter", strconv.Itoa(dc.DataCenterID)), fmt.Sprintf("%s is not used by any property", dc.Nickname))
+				}
+			}
+		},
+	},
+	{
+		ID:          "data-center-location",
+		Severity:    "info",
+		Description: "Every non-virtual data center has a city, country and continent",
+		Check: func(exp *config.Export, cfg lintRuleConfig, report func(string, string)) {
+			for _, dc := range exp.DataCenters {
+				if !dc.Virtual && (dc.City == "" || dc.Country == "" || dc.Continent == "") {
+					report(config.ObjectID("datacenter", strconv.Itoa(dc.DataCenterID)), fmt.Sprintf("%s has an incomplete location", dc.Nickname))
+				}
+			}
+		},
+	},
+}
+
+func lint(c *cli.Context) error {
+	if c.Bool("list-rules") {
+		data := [][]string{}
+		for _, rule := range lintRules {
+			data = append(data, []string{rule.ID, rule.Severity, rule.Description})
+		}
+		render.TableWithHeaders(os.Stdout, []string{"Rule", "Severity", "Description"}, data)
+		return nil
+	}
+
+	cfg := &lintConfig{}
+	if path := c.String("config"); path != "" {
+		if err := config.UnmarshalFile(path, cfg); err != nil {
+			return err
+		}
+	}
+
+	var exp *config.Export
+	var err error
+	if dir := c.String("dir"); dir != "" {
+		exp, err = config.LoadExport(dir)
+	} else {
+		exp, err = config.FetchExport(client(c), c.Args().First())
+	}
+	if err != nil {
+		return err
+	}
+
+	findings, err := runLint(exp, cfg)
+	if err != nil {
+		return err
+	}
+
+	switch c.String("format") {
+	case "", "text":
+		printLintText(findings)
+	case "json":
+		if err := printJSON(findings); err != nil {
+			return err
+		}
+	case "sarif":
+		if err := printJSON(sarifLog(findings, cfg.Custom)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q", c.String("format"))
+	}
+
+	failOn := c.String("fail-on")
+	if failOn == "none" {
+		return nil
+	}
+	threshold := severityRank(failOn)
+	if threshold < 0 {
+		return fmt.Errorf("unknown severity %q", failOn)
+	}
+	failed := 0
+	for _, f := range findings {
+		if severityRank(f.Severity) >= threshold {
+			failed++
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("%d findings at or above severity %s", failed, failOn)
+	}
+
+	return nil
+}
+
+// runLint runs the enabled built-in rules and the custom rules over exp,
+// dropping suppressed findings.
+func runLint(exp *config.Export, cfg *lintConfig) ([]lintFinding, error) {
+	findings := []lintFinding{}
+	add := func(rule, severity, object, message string) {
+		findings = append(findings, lintFinding{
+			Rule:     rule,
+			Severity: severity,
+			Object:   object,
+			Message:  message,
+			File:     exp.Files[object],
+		})
+	}
+
+	for id := range cfg.Rules {
+		if !isLintRule(id) {
+			return nil, fmt.Errorf("unknown lint rule %q in config", id)
+		}
+	}
+
+	for _, rule := range lintRules {
+		ruleCfg := cfg.Rules[rule.ID]
+		if ruleCfg.Enabled != nil && !*ruleCfg.Enabled {
+			continue
+		}
+		severity := rule.Severity
+		if ruleCfg.Severity != "" {
+			severity = ruleCfg.Severity
+		}
+		if severityRank(severity) < 0 {
+			return nil, fmt.Errorf("unknown severity %q for rule %s", severity, rule.ID)
+		}
+		id := rule.ID
+		rule.Check(exp, ruleCfg, func(object, message string) {
+			add(id, severity, object, message)
+		})
+	}
+
+	props := gtm.SortedProperties(&edgegrid.Properties{Properties: exp.Properties})
+	for _, rule := range cfg.Custom {
+		severity := rule.Severity
+		if severity == "" {
+			severity = "warning"
+		}
+		if rule.ID == "" || severityRank(severity) < 0 {
+			return nil, fmt.Errorf("custom lint rule %q needs an id and a valid severity", rule.ID)
+		}
+
+		var cols columns
+		items := []interface{}{}
+		ids := []string{}
+		switch rule.Object {
+		case "property":
+			cols = propertyColumns()
+			for _, prop := range props {
+				items = append(items, prop)
+				ids = append(ids, config.ObjectID("property", prop.GtmProperty.Name))
+			}
+		case "datacenter":
+			cols = dataCenterColumns()
+			for _, dc := range exp.DataCenters {
+				items = append(items, dc)
+				ids = append(ids, config.ObjectID("datacenter", strconv.Itoa(dc.DataCenterID)))
+			}
+		default:
+			return nil, fmt.Errorf("custom lint rule %s: object must be property or datacenter", rule.ID)
+		}
+
+		filters := []filter{}
+		for _, expr := range rule.When {
+			f, err := parseFilter(expr, cols)
+			if err != nil {
+				return nil, fmt.Errorf("custom lint rule %s: %v", rule.ID, err)
+			}
+			filters = append(filters, f)
+		}
+
+		for i, item := range items {
+			matched := len(filters) != 0
+			for _, f := range filters {
+				matched = matched && f.match(item)
+			}
+			if matched {
+				add(rule.ID, severity, ids[i], rule.Message)
+			}
+		}
+	}
+
+	ignored := inlineSuppressions(exp)
+	kept := []lintFinding{}
+	for _, f := range findings {
+		if !suppressed(f, cfg.Suppress, ignored[f.Object]) {
+			kept = append(kept, f)
+		}
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].Severity != kept[j].Severity {
+			return severityRank(kept[i].Severity) > severityRank(kept[j].Severity)
+		}
+		return kept[i].Object < kept[j].Object
+	})
+
+	return kept, nil
+}
+
+func isLintRule(id string) bool {
+	for _, rule := range lintRules {
+		if rule.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inlineSuppressions returns the rules suppressed by "lint:ignore"
+// directives in property comments, keyed by object ID.
+func inlineSuppressions(exp *config.Export) map[string][]string {
+	ignored := map[string][]string{}
+	for _, prop := range exp.Properties {
+		for _, m := range lintIgnorePattern.FindAllStringSubmatch(prop.Comments, -1) {
+			id := config.ObjectID("property", prop.Name)
+			ignored[id] = append(ignored[id], strings.Split(m[1], ",")...)
+		}
+	}
+
+	return ignored
+}
+
+func suppressed(f lintFinding, suppressions []lintSuppression, inline []string) bool {
+	for _, rule := range inline {
+		if ok, _ := path.Match(rule, f.Rule); ok {
+			return true
+		}
+	}
+	for _, s := range suppressions {
+		rule, object := s.Rule, s.Object
+		if rule == "" {
+			rule = "*"
+		}
+		if object == "" {
+			object = "*"
+		}
+		ruleOk, _ := path.Match(rule, f.Rule)
+		objectOk, _ := path.Match(object, f.Object)
+		if ruleOk && objectOk {
+			return true
+		}
+	}
+
+	return false
+}
+
+func severityRank(severity string) int {
+	for i, s := range lintSeverities {
+		if s == severity {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func printLintText(findings []lintFinding) {
+	if len(findings) == 0 {
+		fmt.Println("No problems found")
+		return
+	}
+
+	counts := map[string]int{}
+	data := [][]string{}
+	for _, f := range findings {
+		counts[f.Severity]++
+		data = append(data, []string{f.Severity, f.Rule, f.Object, f.Message})
+	}
+	render.TableWithHeaders(os.Stdout, []string{"Severity", "Rule", "Object", "Message"}, data)
+	fmt.Printf("%d errors, %d warnings, %d info\n", counts["error"], counts["warning"], counts["info"])
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// sarifLog converts findings to a SARIF 2.1.0 log. Findings from an export
+// directory are located by file; live findings by logical location.
+func sarifLog(findings []lintFinding, custom []customLintRule) map[string]interface{} {
+	levels := map[string]string{"error": "error", "warning": "warning", "info": "note"}
+
+	rules := []interface{}{}
+	for _, rule := range lintRules {
+		rules = append(rules, map[string]interface{}{
+			"id":                   rule.ID,
+			"shortDescription":     map[string]string{"text": rule.Description},
+			"defaultConfiguration": map[string]string{"level": levels[rule.Severity]},
+		})
+	}
+	for _, rule := range custom {
+		severity := rule.Severity
+		if severity == "" {
+			severity = "warning"
+		}
+		rules = append(rules, map[string]interface{}{
+			"id":                   rule.ID,
+			"shortDescription":     map[string]string{"text": rule.Message},
+			"defaultConfiguration": map[string]string{"level": levels[severity]},
+		})
+	}
+
+	results := []interface{}{}
+	for _, f := range findings {
+		location := map[string]interface{}{
+			"logicalLocations": []interface{}{
+				map[string]string{"fullyQualifiedName": f.Object},
+			},
+		}
+		if f.File != "" {
+			location["physicalLocation"] = map[string]interface{}{
+				"artifactLocation": map[string]string{"uri": f.File},
+			}
+		}
+		results = append(results, map[string]interface{}{
+			"ruleId":    f.Rule,
+			"level":     levels[f.Severity],
+			"message":   map[string]string{"text": f.Object + " " + f.Message},
+			"locations": []interface{}{location},
+		})
+	}
+
+	return map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":           "akamai-gtm lint",
+						"version":        version,
+						"informationUri": "https://github.com/Comcast/akamai-gtm",
+						"rules":          rules,
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+}
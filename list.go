@@ -0,0 +1,448 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// listFlags are shared by the list commands.
+var listFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "filter",
+		Usage: "Only list rows matching column=value, column!=value, column=~regexp, column!~regexp, column<value or column>value; may be repeated",
+	},
+	cli.StringFlag{
+		Name:  "columns",
+		Usage: "A comma-separated list of columns to display",
+	},
+	cli.StringFlag{
+		Name:  "sort-by",
+		Usage: "The column to sort by; prefix with - to sort in descending order",
+	},
+	cli.BoolFlag{
+		Name:  "no-headers",
+		Usage: "Print tab-separated rows without headers or borders",
+	},
+	cli.BoolFlag{
+		Name:  "csv",
+		Usage: "Print rows as CSV, with a header row of column names unless --no-headers is set",
+	},
+}
+
+// column is a named, displayable attribute of a listed item. Columns may
+// have several values, e.g. the data center IDs of a property's traffic
+// targets; a filter matches if any value matches.
+type column struct {
+	name    string
+	header  string
+	aliases []string
+	values  func(item interface{}) []string
+}
+
+func (col column) matches(name string) bool {
+	if strings.EqualFold(col.name, name) {
+		return true
+	}
+	for _, alias := range col.aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (col column) String(item interface{}) string {
+	return strings.Join(col.values(item), ", ")
+}
+
+// columns is the set of columns available to a list command.
+type columns []column
+
+func (cols columns) find(name string) (column, error) {
+	for _, col := range cols {
+		if col.matches(name) {
+			return col, nil
+		}
+	}
+	names := []string{}
+	for _, col := range cols {
+		names = append(names, col.name)
+	}
+
+	return column{}, fmt.Errorf("unknown column %q; available columns are: %s", name, strings.Join(names, ", "))
+}
+
+func (cols columns) choose(names string, defaults []string) (columns, error) {
+	selected := columns{}
+	list := defaults
+	if names != "" {
+		list = strings.Split(names, ",")
+	}
+	for _, name := range list {
+		col, err := cols.find(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, col)
+	}
+
+	return selected, nil
+}
+
+// structColumns returns a column for each exported field of the struct
+// type t, named after the field and aliased by its JSON name. get returns
+// the struct value for a listed item.
+func structColumns(t reflect.Type, get func(item interface{}) reflect.Value) columns {
+	cols := columns{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		index := i
+		cols = append(cols, column{
+			name:    field.Name,
+			header:  field.Name,
+			aliases: []string{strings.Split(field.Tag.Get("json"), ",")[0]},
+			values: func(item interface{}) []string {
+				return valueStrings(get(item).Field(index))
+			},
+		})
+	}
+
+	return cols
+}
+
+// withColumns replaces any columns of the same name in cols and appends
+// the rest.
+func (cols columns) withColumns(overrides ...column) columns {
+	result := append(columns{}, cols...)
+	for _, override := range overrides {
+		replaced := false
+		for i, col := range result {
+			if strings.EqualFold(col.name, override.name) {
+				if override.aliases == nil {
+					override.aliases = col.aliases
+				}
+				result[i] = override
+				replaced = true
+			}
+		}
+		if !replaced {
+			result = append(result, override)
+		}
+	}
+
+	return result
+}
+
+func valueStrings(v reflect.Value) []string {
+	switch v.Kind() {
+	case reflect.String:
+		return []string{v.String()}
+	case reflect.Bool:
+		return []string{strconv.FormatBool(v.Bool())}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(v.Int(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return []string{render.FloatToStr(v.Float())}
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return []string{""}
+		}
+		if v.Kind() == reflect.Interface {
+			return []string{render.InterfaceToStr(v.Interface())}
+		}
+		return valueStrings(v.Elem())
+	case reflect.Slice:
+		s := []string{}
+		for i := 0; i < v.Len(); i++ {
+			s = append(s, valueStrings(v.Index(i))...)
+		}
+		return s
+	default:
+		return []string{fmt.Sprint(v.Interface())}
+	}
+}
+
+// filter is a parsed --filter expression.
+type filter struct {
+	col    column
+	op     string
+	value  string
+	regexp *regexp.Regexp
+}
+
+var filterPattern = regexp.MustCompile(`^([A-Za-z0-9_]+)\s*(!=|=~|!~|=|<|>)\s*(.*)$`)
+
+func parseFilter(expr string, cols columns) (filter, error) {
+	m := filterPattern.FindStringSubmatch(expr)
+	if m == nil {
+		return filter{}, fmt.Errorf("invalid filter %q", expr)
+	}
+	col, err := cols.find(m[1])
+	if err != nil {
+		return filter{}, err
+	}
+	f := filter{col: col, op: m[2], value: m[3]}
+	if f.op == "=~" || f.op == "!~" {
+		if f.regexp, err = regexp.Compile(f.value); err != nil {
+			return filter{}, fmt.Errorf("invalid filter %q: %v", expr, err)
+		}
+	}
+
+	return f, nil
+}
+
+func (f filter) match(item interface{}) bool {
+	values := f.col.values(item)
+	anyValue := func(pred func(string) bool) bool {
+		for _, v := range values {
+			if pred(v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch f.op {
+	case "=":
+		return anyValue(func(v string) bool { return valuesEqual(v, f.value) })
+	case "!=":
+		return !anyValue(func(v string) bool { return valuesEqual(v, f.value) })
+	case "=~":
+		return anyValue(f.regexp.MatchString)
+	case "!~":
+		return !anyValue(f.regexp.MatchString)
+	case "<":
+		return anyValue(func(v string) bool { return compareValues(v, f.value) < 0 })
+	case ">":
+		return anyValue(func(v string) bool { return compareValues(v, f.value) > 0 })
+	}
+
+	return false
+}
+
+func valuesEqual(a, b string) bool {
+	if af, bf, ok := parseFloats(a, b); ok {
+		return af == bf
+	}
+
+	return strings.EqualFold(a, b)
+}
+
+// compareValues compares numerically when both values are numbers and
+// lexically otherwise.
+func compareValues(a, b string) int {
+	if af, bf, ok := parseFloats(a, b); ok {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(a, b)
+}
+
+func parseFloats(a, b string) (float64, float64, bool) {
+	af, aErr := strconv.ParseFloat(a, 64)
+	bf, bErr := strconv.ParseFloat(b, 64)
+
+	return af, bf, aErr == nil && bErr == nil
+}
+
+// printList filters, sorts and prints items to w according to the list
+// flags, returning the number of rows printed. Items are left in their given order
+// unless --sort-by is set.
+func printList(w io.Writer, c *cli.Context, items []interface{}, cols columns, defaults []string) (int, error) {
+	selected, err := cols.choose(c.String("columns"), defaults)
+	if err != nil {
+		return 0, err
+	}
+
+	filters := []filter{}
+	for _, expr := range c.StringSlice("filter") {
+		f, err := parseFilter(expr, cols)
+		if err != nil {
+			return 0, err
+		}
+		filters = append(filters, f)
+	}
+
+	matched := []interface{}{}
+	for _, item := range items {
+		ok := true
+		for _, f := range filters {
+			if !f.match(item) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, item)
+		}
+	}
+
+	if sortBy := c.String("sort-by"); sortBy != "" {
+		desc := strings.HasPrefix(sortBy, "-")
+		col, err := cols.find(strings.TrimPrefix(sortBy, "-"))
+		if err != nil {
+			return 0, err
+		}
+		sort.SliceStable(matched, func(i, j int) bool {
+			cmp := compareValues(col.String(matched[i]), col.String(matched[j]))
+			if desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+	}
+
+	data := [][]string{}
+	for _, item := range matched {
+		row := []string{}
+		for _, col := range selected {
+			row = append(row, col.String(item))
+		}
+		data = append(data, row)
+	}
+
+	if c.Bool("csv") {
+		return len(data), writeCSV(w, selected, data, !c.Bool("no-headers"))
+	}
+
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	if c.Bool("no-headers") {
+		for _, row := range data {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return len(data), nil
+	}
+
+	headers := []string{}
+	for _, col := range selected {
+		headers = append(headers, col.header)
+	}
+	render.TableWithHeaders(w, headers, data)
+
+	return len(data), nil
+}
+
+// writeCSV writes rows as CSV, preceded by the column names if header is
+// set. Unlike the table headers, the names can be given back to --columns
+// and to data-centers-import, which ignores the read-only ones such as
+// DataCenterID.
+func writeCSV(w io.Writer, cols columns, data [][]string, header bool) error {
+	out := csv.NewWriter(w)
+	if header {
+		names := []string{}
+		for _, col := range cols {
+			names = append(names, col.name)
+		}
+		if err := out.Write(names); err != nil {
+			return err
+		}
+	}
+	if err := out.WriteAll(data); err != nil {
+		return err
+	}
+
+	return out.Error()
+}
+
+func propertyColumns() columns {
+	prop := func(item interface{}) edgegrid.Property {
+		return item.(gtm.Property).GtmProperty
+	}
+
+	return structColumns(reflect.TypeOf(edgegrid.Property{}), func(item interface{}) reflect.Value {
+		return reflect.ValueOf(prop(item))
+	}).withColumns(
+		column{
+			name:    "TrafficTargets",
+			header:  "Traffic Targets",
+			aliases: []string{"trafficTargets", "targets", "dc"},
+			values: func(item interface{}) []string {
+				return render.TargetIDs(prop(item).TrafficTargets)
+			},
+		},
+		column{
+			name:   "LivenessTests",
+			header: "LivenessTests",
+			values: func(item interface{}) []string {
+				return render.LivenessTestNames(prop(item).LivenessTests)
+			},
+		},
+		column{
+			name:   "Enabled",
+			header: "Enabled",
+			values: func(item interface{}) []string {
+				s := []string{}
+				for _, t := range prop(item).TrafficTargets {
+					s = append(s, strconv.FormatBool(t.Enabled))
+				}
+				return s
+			},
+		},
+		column{
+			name:   "Servers",
+			header: "Servers",
+			values: func(item interface{}) []string {
+				s := []string{}
+				for _, t := range prop(item).TrafficTargets {
+					s = append(s, t.Servers...)
+				}
+				return s
+			},
+		},
+		column{
+			name:   "Product",
+			header: "Product",
+			values: func(item interface{}) []string {
+				return []string{item.(gtm.Property).Product}
+			},
+		},
+	)
+}
+
+func dataCenterColumns() columns {
+	return structColumns(reflect.TypeOf(edgegrid.DataCenter{}), func(item interface{}) reflect.Value {
+		return reflect.ValueOf(item.(edgegrid.DataCenter))
+	}).withColumns(
+		column{
+			name:    "DataCenterID",
+			header:  "DataCenter ID",
+			aliases: []string{"datacenterId", "id", "dc"},
+			values: func(item interface{}) []string {
+				return []string{strconv.Itoa(item.(edgegrid.DataCenter).DataCenterID)}
+			},
+		},
+		column{
+			name:    "Nickname",
+			header:  "Nickname",
+			aliases: []string{"nickname", "name"},
+			values: func(item interface{}) []string {
+				return []string{item.(edgegrid.DataCenter).Nickname}
+			},
+		},
+	)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/comcast/akamai-gtm/auth"
+	"github.com/comcast/akamai-gtm/gtmmock"
+	"github.com/urfave/cli"
+)
+
+var mockServerFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "listen",
+		Value: "127.0.0.1:8443",
+		Usage: "The address to listen on",
+	},
+	cli.StringFlag{
+		Name:  "dir",
+		Usage: "Load Domains from an export directory, or a directory of them",
+	},
+	cli.BoolFlag{
+		Name:  "persist",
+		Usage: "Write changes back to --dir",
+	},
+	cli.StringSliceFlag{
+		Name:  "domain",
+		Usage: "Create an empty Domain, as <name>[:<type>]; may be repeated",
+	},
+	cli.DurationFlag{
+		Name:  "delay",
+		Usage: "How long changes stay PENDING before propagation is COMPLETE",
+	},
+	cli.StringSliceFlag{
+		Name:  "fail",
+		Usage: "Fail matching requests, as <status>[,method=<method>][,path=<regexp>][,rate=<0-1>][,count=<n>]; may be repeated",
+	},
+	cli.BoolFlag{
+		Name:  "check-signature",
+		Usage: "Reject requests not signed with the client credentials",
+	},
+	cli.StringFlag{
+		Name:  "tls-cert",
+		Usage: "The TLS certificate file; a self-signed certificate is generated if unset",
+	},
+	cli.StringFlag{
+		Name:  "tls-key",
+		Usage: "The TLS key file",
+	},
+	cli.StringFlag{
+		Name:  "cert-out",
+		Usage: "Write the generated self-signed certificate to this file",
+	},
+	cli.BoolFlag{
+		Name:  "plain-http",
+		Usage: "Serve plain HTTP instead of HTTPS",
+	},
+}
+
+func mockServer(c *cli.Context) error {
+	server := gtmmock.New()
+	server.PropagationDelay = c.Duration("delay")
+	server.Logger = log.New(os.Stderr, "", log.LstdFlags)
+
+	if dir := c.String("dir"); dir != "" {
+		if err := server.Load(dir, c.Bool("persist")); err != nil {
+			return err
+		}
+	} else if c.Bool("persist") {
+		return fmt.Errorf("--persist requires --dir")
+	}
+	for _, spec := range c.StringSlice("domain") {
+		parts := strings.SplitN(spec, ":", 2)
+		if len(parts) == 1 {
+			parts = append(parts, "weighted")
+		}
+		server.AddDomain(parts[0], parts[1])
+	}
+	for _, spec := range c.StringSlice("fail") {
+		failure, err := gtmmock.ParseFailure(spec)
+		if err != nil {
+			return err
+		}
+		server.Failures = append(server.Failures, failure)
+	}
+	if c.Bool("check-signature") {
+		creds := auth.Credentials{
+			ClientToken:  c.GlobalString("client_token"),
+			ClientSecret: c.GlobalString("client_secret"),
+			AccessToken:  c.GlobalString("access_token"),
+		}
+		if creds.ClientToken == "" || creds.ClientSecret == "" || creds.AccessToken == "" {
+			return fmt.Errorf("--check-signature requires --client_token, --client_secret and --access_token")
+		}
+		server.Credentials = &creds
+	}
+
+	listener, err := net.Listen("tcp", c.String("listen"))
+	if err != nil {
+		return err
+	}
+	addr := listener.Addr().String()
+
+	if c.Bool("plain-http") {
+		fmt.Fprintf(os.Stderr, "Serving %d Domains on http://%s\n", len(server.Domains()), addr)
+		return http.Serve(listener, server)
+	}
+
+	cert, err := mockCertificate(c, addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Serving %d Domains on https://%s; use --host %s\n", len(server.Domains()), addr, addr)
+
+	return http.Serve(tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{cert}}), server)
+}
+
+// mockCertificate loads --tls-cert and --tls-key, or generates a
+// self-signed certificate for addr and writes it to --cert-out, so that
+// clients can trust it through SSL_CERT_FILE.
+func mockCertificate(c *cli.Context, addr string) (tls.Certificate, error) {
+	if c.String("tls-cert") != "" || c.String("tls-key") != "" {
+		return tls.LoadX509KeyPair(c.String("tls-cert"), c.String("tls-key"))
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "akamai-gtm mock-server"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else if host != "" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	certOut := c.String("cert-out")
+	if certOut == "" {
+		certOut = filepath.Join(os.TempDir(), "akamai-gtm-mock.pem")
+	}
+	if err := ioutil.WriteFile(certOut, certPEM, 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote self-signed certificate to %s; use SSL_CERT_FILE=%s\n", certOut, certOut)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
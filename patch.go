@@ -0,0 +1,608 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// patchFlags are shared by the *-patch commands.
+var patchFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "set",
+		Usage: "A field to change as path=value, e.g. trafficTargets.0.weight=50; may be repeated",
+	},
+	cli.StringFlag{
+		Name:  "merge-patch",
+		Usage: "The path to an RFC 7386 JSON merge patch file, or - for stdin",
+	},
+	cli.StringFlag{
+		Name:  "json-patch",
+		Usage: "The path to an RFC 6902 JSON patch file, or - for stdin",
+	},
+	cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Show the resulting diff without submitting the update",
+	},
+}
+
+func propertyPatch(c *cli.Context) error {
+	domain := c.Args().First()
+	client := client(c)
+	prop, err := client.Property(domain, c.String("name"))
+	if err != nil {
+		return err
+	}
+
+	patched := &edgegrid.Property{}
+	if ok, err := patchObject(c, prop, patched); !ok || err != nil {
+		return err
+	}
+
+	resp, err := client.PropertyUpdate(domain, patched)
+	if err != nil {
+		return err
+	}
+
+	render.Property(os.Stdout, resp.Property)
+
+	return nil
+}
+
+func dataCenterPatch(c *cli.Context) error {
+	domain := c.Args().First()
+	client := client(c)
+	dc, err := client.DataCenter(domain, c.Int("id"))
+	if err != nil {
+		return err
+	}
+
+	patched := &edgegrid.DataCenter{}
+	if ok, err := patchObject(c, dc, patched); !ok || err != nil {
+		return err
+	}
+
+	resp, err := client.DataCenterUpdate(domain, patched)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated %s\n", resp.DataCenter.Nickname)
+
+	return nil
+}
+
+func domainPatch(c *cli.Context) error {
+	client := client(c)
+	domain, err := client.Domain(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	patched := &edgegrid.Domain{}
+	if ok, err := patchObject(c, domain, patched); !ok || err != nil {
+		return err
+	}
+
+	resp, err := client.DomainUpdate(patched)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated domain: %s\n", resp.Domain.Name)
+
+	return nil
+}
+
+// patchObject applies the patches given by --merge-patch, --json-patch and
+// --set (in that order) to original, decodes the result into patched and
+// prints the diff between the two. It reports whether the update should be
+// submitted, which is not the case for a dry run or when nothing changed.
+func patchObject(c *cli.Context, original, patched interface{}) (bool, error) {
+	if c.String("merge-patch") == "-" && c.String("json-patch") == "-" {
+		return false, fmt.Errorf("only one of --merge-patch and --json-patch may read from stdin")
+	}
+
+	doc, err := toDocument(original)
+	if err != nil {
+		return false, err
+	}
+
+	if path := c.String("merge-patch"); path != "" {
+		patch, err := readPatchFile(path)
+		if err != nil {
+			return false, err
+		}
+		doc = mergePatch(doc, patch)
+	}
+
+	if path := c.String("json-patch"); path != "" {
+		patch, err := readPatchFile(path)
+		if err != nil {
+			return false, err
+		}
+		if doc, err = applyJSONPatch(doc, patch); err != nil {
+			return false, err
+		}
+	}
+
+	for _, set := range c.StringSlice("set") {
+		kv := strings.SplitN(set, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return false, fmt.Errorf("invalid --set %q: expected path=value", set)
+		}
+		if doc, err = setPath(doc, splitSetPath(kv[0]), parseSetValue(kv[1])); err != nil {
+			return false, fmt.Errorf("--set %s: %v", kv[0], err)
+		}
+	}
+
+	if err := fromDocument(doc, patched); err != nil {
+		return false, err
+	}
+
+	diff, err := jsonDiff("live", "patched", original, patched)
+	if err != nil {
+		return false, err
+	}
+	if diff == "" {
+		fmt.Println("No changes")
+		return false, nil
+	}
+
+	fmt.Print(diff)
+
+	return !c.Bool("dry-run"), nil
+}
+
+// toDocument converts v to its generic JSON representation.
+func toDocument(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeDocument(data)
+}
+
+func fromDocument(doc interface{}, v interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func decodeDocument(data []byte) (interface{}, error) {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// readPatchFile reads a JSON or YAML patch document.
+func readPatchFile(path string) (interface{}, error) {
+	data, err := config.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if config.DetectFormat(path, data) == config.FormatJSON {
+		doc, err := decodeDocument(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return doc, nil
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return config.NormalizeYAML(doc), nil
+}
+
+// mergePatch applies an RFC 7386 JSON merge patch to target.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergePatch(targetObj[k], v)
+	}
+
+	return targetObj
+}
+
+// applyJSONPatch applies an RFC 6902 JSON patch to doc.
+func applyJSONPatch(doc, patch interface{}) (interface{}, error) {
+	ops, ok := patch.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("a JSON patch must be an array of operations")
+	}
+
+	for i, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("JSON patch operation %d is not an object", i)
+		}
+		name, _ := op["op"].(string)
+		path, ok := op["path"].(string)
+		if !ok {
+			return nil, fmt.Errorf("JSON patch operation %d has no path", i)
+		}
+		tokens, err := parsePointer(path)
+		if err != nil {
+			return nil, err
+		}
+
+		switch name {
+		case "add":
+			doc, err = pointerAdd(doc, tokens, op["value"])
+		case "remove":
+			doc, _, err = pointerRemove(doc, tokens)
+		case "replace":
+			if _, err = pointerGet(doc, tokens); err == nil {
+				doc, err = pointerReplace(doc, tokens, op["value"])
+			}
+		case "move", "copy":
+			from, ok := op["from"].(string)
+			if !ok {
+				return nil, fmt.Errorf("JSON patch operation %d (%s) has no from", i, name)
+			}
+			fromTokens, perr := parsePointer(from)
+			if perr != nil {
+				return nil, perr
+			}
+			var value interface{}
+			if name == "move" {
+				doc, value, err = pointerRemove(doc, fromTokens)
+			} else {
+				value, err = pointerGet(doc, fromTokens)
+				value = deepCopy(value)
+			}
+			if err == nil {
+				doc, err = pointerAdd(doc, tokens, value)
+			}
+		case "test":
+			var value interface{}
+			if value, err = pointerGet(doc, tokens); err == nil && !jsonEqual(value, op["value"]) {
+				err = fmt.Errorf("test failed: %s does not match", path)
+			}
+		default:
+			return nil, fmt.Errorf("JSON patch operation %d has unknown op %q", i, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("JSON patch operation %d (%s %s): %v", i, name, path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+
+	return tokens, nil
+}
+
+func pointerGet(doc interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("%q not found", t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("cannot index %q into a scalar", t)
+		}
+	}
+
+	return doc, nil
+}
+
+// pointerUpdate locates the parent of the value addressed by tokens and
+// calls fn with it, replacing the parent with fn's result.
+func pointerUpdate(doc interface{}, tokens []string, fn func(parent interface{}, last string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("%q not found", tokens[0])
+		}
+		updated, err := pointerUpdate(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[tokens[0]] = updated
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(tokens[0], len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := pointerUpdate(node[i], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = updated
+		return node, nil
+	default:
+		return nil, fmt.Errorf("cannot index %q into a scalar", tokens[0])
+	}
+}
+
+func pointerAdd(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return pointerUpdate(doc, tokens, func(parent interface{}, last string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[last] = value
+			return node, nil
+		case []interface{}:
+			if last == "-" {
+				return append(node, value), nil
+			}
+			i, err := arrayIndex(last, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("cannot add %q to a scalar", last)
+		}
+	})
+}
+
+func pointerReplace(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return pointerUpdate(doc, tokens, func(parent interface{}, last string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[last] = value
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(last, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("cannot replace %q in a scalar", last)
+		}
+	})
+}
+
+func pointerRemove(doc interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+
+	var removed interface{}
+	doc, err := pointerUpdate(doc, tokens, func(parent interface{}, last string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			v, ok := node[last]
+			if !ok {
+				return nil, fmt.Errorf("%q not found", last)
+			}
+			removed = v
+			delete(node, last)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(last, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove %q from a scalar", last)
+		}
+	})
+
+	return doc, removed, err
+}
+
+func arrayIndex(token string, maxIndex int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > maxIndex {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+
+	return i, nil
+}
+
+// splitSetPath splits a --set path such as trafficTargets[0].weight or
+// trafficTargets.0.weight into its components.
+func splitSetPath(path string) []string {
+	path = strings.Replace(path, "[", ".", -1)
+	path = strings.Replace(path, "]", "", -1)
+
+	return strings.Split(strings.Trim(path, "."), ".")
+}
+
+// parseSetValue interprets a --set value as JSON when it is valid JSON
+// (numbers, booleans, null, quoted strings, arrays and objects) and as a
+// plain string otherwise.
+func parseSetValue(value string) interface{} {
+	if doc, err := decodeDocument([]byte(value)); err == nil {
+		return doc
+	}
+
+	return value
+}
+
+// setPath sets the value at path. Object keys are matched exactly first
+// and then case-insensitively, so the Go field names shown by the property
+// and data-center commands (e.g. HandoutMode) may be used in place of the
+// API names (handoutMode). Missing intermediate objects are created.
+func setPath(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		key := matchKey(node, path[0])
+		updated, err := setPath(node[key], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		node[key] = updated
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(path[0], len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := setPath(node[i], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = updated
+		return node, nil
+	case nil:
+		return setPath(map[string]interface{}{}, path, value)
+	default:
+		return nil, fmt.Errorf("cannot set %q on a scalar", path[0])
+	}
+}
+
+func matchKey(node map[string]interface{}, key string) string {
+	if _, ok := node[key]; ok {
+		return key
+	}
+	for k := range node {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+
+	return key
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range t {
+			m[k] = deepCopy(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, val := range t {
+			s[i] = deepCopy(val)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// jsonEqual compares two generic JSON values, treating numbers as equal
+// when they have the same value regardless of representation.
+func jsonEqual(a, b interface{}) bool {
+	switch at := a.(type) {
+	case map[string]interface{}:
+		bt, ok := b.(map[string]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for k, v := range at {
+			if bv, ok := bt[k]; !ok || !jsonEqual(v, bv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bt, ok := b.([]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for i := range at {
+			if !jsonEqual(at[i], bt[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number, float64, int:
+		af, aok := toFloat(a)
+		bf, bok := toFloat(b)
+		return aok && bok && af == bf
+	default:
+		return a == b
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	default:
+		return 0, false
+	}
+}
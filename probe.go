@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// defaultTestTimeout is used when a liveness test has no TestTimeout; it
+// matches the GTM default.
+const defaultTestTimeout = 25 * time.Second
+
+// maxProbeBody limits how much of a response is searched for the
+// ResponseString.
+const maxProbeBody = 1 << 20
+
+var defaultPorts = map[string]int64{
+	"HTTP":  80,
+	"HTTPS": 443,
+	"FTP":   21,
+}
+
+// probeResult is the outcome of running one liveness test against one
+// server.
+type probeResult struct {
+	Test       string
+	DataCenter int
+	Server     string
+	Result     string
+	Duration   time.Duration
+	Detail     string
+}
+
+// errProbeSkipped is returned for liveness test protocols that cannot be
+// run locally.
+var errProbeSkipped = fmt.Errorf("protocol not supported locally")
+
+func livenessProbe(c *cli.Context) error {
+	prop, err := client(c).Property(c.Args().First(), c.String("name"))
+	if err != nil {
+		return err
+	}
+
+	results := runProbes(prop, c.Bool("include-disabled"), c.String("test"))
+	if len(results) == 0 {
+		fmt.Printf("No liveness tests or servers to probe for property: %s\n", prop.Name)
+		return nil
+	}
+
+	data := [][]string{}
+	failed := 0
+	for _, r := range results {
+		if r.Result == "FAIL" {
+			failed++
+		}
+		data = append(data, []string{
+			r.Test,
+			strconv.Itoa(r.DataCenter),
+			r.Server,
+			r.Result,
+			fmt.Sprintf("%.0fms", r.Duration.Seconds()*1000),
+			r.Detail,
+		})
+	}
+	render.TableWithHeaders(os.Stdout, []string{"Test", "DC", "Server", "Result", "Time", "Detail"}, data)
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d probes failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// runProbes runs each liveness test of prop, or only the one named by
+// testName if it is set, against every server of its traffic targets
+// concurrently, returning the results in test, target and server order.
+func runProbes(prop *edgegrid.Property, includeDisabled bool, testName string) []probeResult {
+	results := []probeResult{}
+	// tests[i] is the liveness test of results[i]; tests are not looked up
+	// by name, which need not be unique
+	tests := []edgegrid.LivenessTest{}
+	for _, test := range prop.LivenessTests {
+		if testName != "" && test.Name != testName {
+			continue
+		}
+		for _, target := range prop.TrafficTargets {
+			if !target.Enabled && !includeDisabled {
+				continue
+			}
+			for _, server := range target.Servers {
+				results = append(results, probeResult{
+					Test:       test.Name,
+					DataCenter: target.DataCenterID,
+					Server:     server,
+				})
+				tests = append(tests, test)
+			}
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func(r *probeResult, test edgegrid.LivenessTest) {
+			defer wg.Done()
+			start := time.Now()
+			detail, err := probe(test, r.Server)
+			r.Duration = time.Since(start)
+			switch {
+			case err == errProbeSkipped:
+				r.Result = "SKIP"
+				r.Detail = fmt.Sprintf("%s: %v", test.TestObjectProtocol, err)
+			case err != nil:
+				r.Result = "FAIL"
+				r.Detail = err.Error()
+			default:
+				r.Result = "PASS"
+				r.Detail = detail
+			}
+		}(&results[i], tests[i])
+	}
+	wg.Wait()
+
+	return results
+}
+
+// probe runs a single liveness test against server, returning a short
+// description of the response on success.
+func probe(test edgegrid.LivenessTest, server string) (string, error) {
+	protocol := strings.ToUpper(test.TestObjectProtocol)
+	port := test.TestObjectPort
+	if port == 0 {
+		port = defaultPorts[protocol]
+	}
+	if port == 0 {
+		return "", fmt.Errorf("no port configured")
+	}
+	addr := net.JoinHostPort(server, strconv.FormatInt(port, 10))
+
+	timeout := defaultTestTimeout
+	if test.TestTimeout > 0 {
+		timeout = time.Duration(test.TestTimeout * float64(time.Second))
+	}
+
+	switch protocol {
+	case "HTTP", "HTTPS":
+		return probeHTTP(test, protocol, addr, timeout)
+	case "TCP", "TCPS":
+		return probeTCP(test, protocol == "TCPS", addr, timeout)
+	case "FTP":
+		return probeFTP(test, addr, timeout)
+	default:
+		return "", errProbeSkipped
+	}
+}
+
+func probeTLSConfig(test edgegrid.LivenessTest, server string) (*tls.Config, error) {
+	config := &tls.Config{
+		// like GTM, liveness tests do not validate server certificates
+		InsecureSkipVerify: true,
+		ServerName:         test.HostHeader,
+	}
+	if config.ServerName == "" {
+		config.ServerName = server
+	}
+	if test.SSLCertificate != "" || test.SSLClientPrivateKey != "" {
+		cert, err := tls.X509KeyPair([]byte(test.SSLCertificate), []byte(test.SSLClientPrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSL client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+func probeHTTP(test edgegrid.LivenessTest, protocol, addr string, timeout time.Duration) (string, error) {
+	host, _, _ := net.SplitHostPort(addr)
+	tlsConfig, err := probeTLSConfig(test, host)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	path := test.TestObject
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	req, err := http.NewRequest("GET", strings.ToLower(protocol)+"://"+addr+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if test.HostHeader != "" {
+		req.Host = test.HostHeader
+	}
+	if test.TestObjectUsername != "" {
+		req.SetBasicAuth(test.TestObjectUsername, test.TestObjectPassword)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400 && test.HTTPError3xx,
+		resp.StatusCode >= 400 && resp.StatusCode < 500 && test.HTTPError4xx,
+		resp.StatusCode >= 500 && test.HTTPError5xx:
+		return "", fmt.Errorf("HTTP %s", resp.Status)
+	}
+
+	if test.ResponseString != "" {
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+		if err != nil {
+			return "", err
+		}
+		if !strings.Contains(string(body), test.ResponseString) {
+			return "", fmt.Errorf("HTTP %s: response does not contain %q", resp.Status, test.ResponseString)
+		}
+	}
+
+	return "HTTP " + resp.Status, nil
+}
+
+func probeDial(test edgegrid.LivenessTest, useTLS bool, addr string, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !useTLS {
+		return dialer.Dial("tcp", addr)
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	tlsConfig, err := probeTLSConfig(test, host)
+	if err != nil {
+		return nil, err
+	}
+
+	return tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+}
+
+func probeTCP(test edgegrid.LivenessTest, useTLS bool, addr string, timeout time.Duration) (string, error) {
+	conn, err := probeDial(test, useTLS, addr, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if test.RequestString != "" {
+		if _, err := io.WriteString(conn, test.RequestString); err != nil {
+			return "", err
+		}
+	}
+	if test.ResponseString == "" {
+		return "connected", nil
+	}
+
+	received := []byte{}
+	buf := make([]byte, 4096)
+	for len(received) < maxProbeBody {
+		n, err := conn.Read(buf)
+		received = append(received, buf[:n]...)
+		if strings.Contains(string(received), test.ResponseString) {
+			return "response matched", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("response does not contain %q: %v", test.ResponseString, err)
+		}
+	}
+
+	return "", fmt.Errorf("response does not contain %q", test.ResponseString)
+}
+
+// probeFTP logs in and retrieves the test object using passive mode.
+func probeFTP(test edgegrid.LivenessTest, addr string, timeout time.Duration) (string, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	r := bufio.NewReader(conn)
+
+	cmd := func(line string, expect ...int) (int, string, error) {
+		if line != "" {
+			if _, err := fmt.Fprintf(conn, "%s\r\n", line); err != nil {
+				return 0, "", err
+			}
+		}
+		code, msg, err := readFTPReply(r)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, e := range expect {
+			if code/100 == e {
+				return code, msg, nil
+			}
+		}
+		if strings.HasPrefix(line, "PASS ") {
+			line = "PASS ***"
+		}
+		return code, msg, fmt.Errorf("%s: %d %s", strings.TrimSpace(line+" -"), code, msg)
+	}
+
+	if _, _, err := cmd("", 2); err != nil {
+		return "", err
+	}
+	user := test.TestObjectUsername
+	if user == "" {
+		user = "anonymous"
+	}
+	code, _, err := cmd("USER "+user, 2, 3)
+	if err != nil {
+		return "", err
+	}
+	if code/100 == 3 {
+		if _, _, err := cmd("PASS "+test.TestObjectPassword, 2); err != nil {
+			return "", err
+		}
+	}
+	if _, _, err := cmd("TYPE I", 2); err != nil {
+		return "", err
+	}
+	_, msg, err := cmd("PASV", 2)
+	if err != nil {
+		return "", err
+	}
+	dataAddr, err := parsePASV(msg, addr)
+	if err != nil {
+		return "", err
+	}
+	data, err := net.DialTimeout("tcp", dataAddr, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer data.Close()
+	data.SetDeadline(time.Now().Add(timeout))
+
+	if _, _, err := cmd("RETR "+test.TestObject, 1); err != nil {
+		return "", err
+	}
+	n, err := io.Copy(ioutil.Discard, io.LimitReader(data, maxProbeBody))
+	if err != nil {
+		return "", err
+	}
+	if _, _, err := cmd("", 2); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("retrieved %d bytes", n), nil
+}
+
+// readFTPReply reads a possibly multi-line FTP reply.
+func readFTPReply(r *bufio.Reader) (int, string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) < 4 {
+		return 0, "", fmt.Errorf("invalid FTP reply %q", line)
+	}
+	code, err := strconv.Atoi(line[:3])
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid FTP reply %q", line)
+	}
+	if line[3] == '-' {
+		end := line[:3] + " "
+		for {
+			next, err := r.ReadString('\n')
+			if err != nil {
+				return 0, "", err
+			}
+			if strings.HasPrefix(next, end) {
+				break
+			}
+		}
+	}
+
+	return code, line[4:], nil
+}
+
+// parsePASV extracts the data connection port from a PASV reply,
+// connecting to the control connection's host as most clients do.
+func parsePASV(msg, controlAddr string) (string, error) {
+	start := strings.Index(msg, "(")
+	end := strings.Index(msg, ")")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("invalid PASV reply %q", msg)
+	}
+	parts := strings.Split(msg[start+1:end], ",")
+	if len(parts) != 6 {
+		return "", fmt.Errorf("invalid PASV reply %q", msg)
+	}
+	hi, err1 := strconv.Atoi(strings.TrimSpace(parts[4]))
+	lo, err2 := strconv.Atoi(strings.TrimSpace(parts[5]))
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("invalid PASV reply %q", msg)
+	}
+	host, _, _ := net.SplitHostPort(controlAddr)
+
+	return net.JoinHostPort(host, strconv.Itoa(hi<<8|lo)), nil
+}
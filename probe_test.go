@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+func TestRunProbesDuplicateTestNames(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := int64(listener.Addr().(*net.TCPAddr).Port)
+
+	prop := &edgegrid.Property{
+		Name: "www",
+		TrafficTargets: []edgegrid.TrafficTarget{
+			{DataCenterID: 3131, Enabled: true, Servers: []string{"127.0.0.1"}},
+		},
+		LivenessTests: []edgegrid.LivenessTest{
+			{Name: "health", TestObjectProtocol: "TCP", TestObjectPort: port},
+			{Name: "health", TestObjectProtocol: "SNMP", TestObjectPort: port},
+		},
+	}
+
+	results := runProbes(prop, false, "")
+	got := []string{}
+	for _, r := range results {
+		got = append(got, r.Result)
+	}
+	if len(got) != 2 || got[0] != "PASS" || got[1] != "SKIP" {
+		t.Errorf("results = %v, want [PASS SKIP] in definition order", got)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/term"
+)
+
+// stdin is shared by the prompts so that input buffered by one is not
+// lost to the next.
+var stdin = bufio.NewReader(os.Stdin)
+
+// stdinIsTerminal reports whether the prompts can be answered. Tests
+// replace it, and stdin, to answer them.
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// prompt asks a question on stderr and returns the line typed in answer.
+// It fails if stdin is not a terminal, naming the flag to use instead.
+func prompt(question, flag string) (string, error) {
+	if !stdinIsTerminal() {
+		return "", fmt.Errorf("stdin is not a terminal: use %s", flag)
+	}
+	fmt.Fprint(os.Stderr, question)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,62 @@
+package render
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// FloatToStr formats a float with six decimal places.
+func FloatToStr(input float64) string {
+	return strconv.FormatFloat(input, 'f', 6, 64)
+}
+
+// InterfaceToStr formats the string and number values of the untyped
+// fields of GTM objects, and returns "" for anything else.
+func InterfaceToStr(inter interface{}) string {
+	if str, ok := inter.(string); ok {
+		return str
+	}
+	if num, ok := inter.(int); ok {
+		return strconv.Itoa(num)
+	}
+	if float, ok := inter.(float64); ok {
+		return FloatToStr(float)
+	}
+
+	return ""
+}
+
+// MaxRecsString joins a property's MX records.
+func MaxRecsString(records []interface{}) string {
+	recs := []string{}
+
+	for _, r := range records {
+		recs = append(recs, r.(string))
+	}
+
+	return strings.Join(recs, ", ")
+}
+
+// TargetIDs returns the data center IDs of traffic targets.
+func TargetIDs(trafficTargets []edgegrid.TrafficTarget) []string {
+	targets := []string{}
+
+	for _, t := range trafficTargets {
+		targets = append(targets, strconv.Itoa(t.DataCenterID))
+	}
+
+	return targets
+}
+
+// LivenessTestNames returns the names of liveness tests.
+func LivenessTestNames(livenessTests []edgegrid.LivenessTest) []string {
+	tests := []string{}
+
+	for _, t := range livenessTests {
+		tests = append(tests, t.Name)
+	}
+
+	return tests
+}
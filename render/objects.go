@@ -0,0 +1,166 @@
+package render
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// Domain writes the details of a domain.
+func Domain(w io.Writer, domain *edgegrid.Domain) {
+	BasicTable(w, DomainRows(domain))
+}
+
+// DomainRows returns the details of a domain as name, value rows.
+func DomainRows(domain *edgegrid.Domain) [][]string {
+	dcs := []string{}
+	for _, dc := range domain.Datacenters {
+		dcs = append(dcs, dc.Nickname)
+	}
+	data := [][]string{
+		[]string{"Name", domain.Name},
+		[]string{"Type", domain.Type},
+		[]string{"DataCenters", strings.Join(dcs, ", ")},
+		[]string{"Status", domain.Status.Message},
+		[]string{"Propagation Status", domain.Status.PropagationStatus},
+		[]string{"Last Modified By", domain.LastModifiedBy},
+		[]string{"Last Modified", domain.LastModified},
+		[]string{"Modification Comments", domain.ModificationComments},
+	}
+
+	return data
+}
+
+// Status writes the propagation status of a domain.
+func Status(w io.Writer, status *edgegrid.DomainStatus) {
+	BasicTable(w, StatusRows(status))
+}
+
+// StatusRows returns the propagation status of a domain as name, value
+// rows.
+func StatusRows(status *edgegrid.DomainStatus) [][]string {
+	data := [][]string{
+		[]string{"PropagationStatus", status.PropagationStatus},
+		[]string{"PassingValidation", strconv.FormatBool(status.PassingValidation)},
+		[]string{"Message", status.Message},
+		[]string{"ChangeID", status.ChangeID},
+		[]string{"PropagationStatusDate", status.PropagationStatusDate},
+	}
+
+	return data
+}
+
+// DataCenter writes the details of a data center.
+func DataCenter(w io.Writer, dc *edgegrid.DataCenter) {
+	data := [][]string{
+		[]string{"Nickname", dc.Nickname},
+		[]string{"DataCenterID", strconv.Itoa(dc.DataCenterID)},
+		[]string{"City", dc.City},
+		[]string{"CloneOf", strconv.Itoa(dc.CloneOf)},
+		[]string{"Continent", dc.Continent},
+		[]string{"Country", dc.Country},
+		[]string{"Latitude", FloatToStr(dc.Latitude)},
+		[]string{"Longitude", FloatToStr(dc.Longitude)},
+		[]string{"StateOrProvince", dc.StateOrProvince},
+		[]string{"Virtual", strconv.FormatBool(dc.Virtual)},
+		[]string{"CloudServerTargeting", strconv.FormatBool(dc.CloudServerTargeting)},
+	}
+
+	BasicTable(w, data)
+}
+
+// Property writes the details of a property.
+func Property(w io.Writer, prop *edgegrid.Property) {
+	data := [][]string{
+		[]string{"BackupCname", prop.BackupCname},
+		[]string{"BackupIP", prop.BackupIP},
+		[]string{"BalanceByDownloadScore", strconv.FormatBool(prop.BalanceByDownloadScore)},
+		[]string{"Cname", prop.Cname},
+		[]string{"Comments", prop.Comments},
+		[]string{"DynamicTTL", strconv.Itoa(prop.DynamicTTL)},
+		[]string{"FailbackDelay", strconv.Itoa(prop.FailbackDelay)},
+		[]string{"FailoverDelay", strconv.Itoa(prop.FailoverDelay)},
+		[]string{"HandoutMode", prop.HandoutMode},
+		[]string{"HealthMax", FloatToStr(prop.HealthMax)},
+		[]string{"HealthMultiplier", FloatToStr(prop.HealthMultiplier)},
+		[]string{"HealthThreshold", FloatToStr(prop.HealthThreshold)},
+		[]string{"Ipv6", strconv.FormatBool(prop.Ipv6)},
+		[]string{"LastModified", prop.LastModified},
+		[]string{"LivenessTests", strings.Join(LivenessTestNames(prop.LivenessTests), ", ")},
+		[]string{"LoadImbalancePercentage", FloatToStr(prop.LoadImbalancePercentage)},
+		[]string{"MapName", InterfaceToStr(prop.MapName)},
+		[]string{"MaxUnreachablePenalty", InterfaceToStr(prop.MaxUnreachablePenalty)},
+		[]string{"MxRecords", MaxRecsString(prop.MxRecords)},
+		[]string{"Name", prop.Name},
+		[]string{"ScoreAggregationType", prop.ScoreAggregationType},
+		[]string{"StaticTTL", InterfaceToStr(prop.StaticTTL)},
+		[]string{"StickinessBonusConstant", InterfaceToStr(prop.StickinessBonusConstant)},
+		[]string{"StickinessBonusPercentage", InterfaceToStr(prop.StickinessBonusPercentage)},
+		[]string{"TrafficTargets", strings.Join(TargetIDs(prop.TrafficTargets), ", ")},
+		[]string{"Type", prop.Type},
+		[]string{"UnreachableThreshold", InterfaceToStr(prop.UnreachableThreshold)},
+		[]string{"UseComputedTargets", strconv.FormatBool(prop.UseComputedTargets)},
+	}
+
+	BasicTable(w, data)
+}
+
+// TrafficTarget writes the details of a traffic target.
+func TrafficTarget(w io.Writer, target edgegrid.TrafficTarget) {
+	BasicTable(w, TrafficTargetRows(target))
+}
+
+// TrafficTargetRows returns the details of a traffic target as name, value
+// rows.
+func TrafficTargetRows(target edgegrid.TrafficTarget) [][]string {
+	data := [][]string{
+		[]string{"Name", InterfaceToStr(target.Name)},
+		[]string{"DCId", strconv.Itoa(target.DataCenterID)},
+		[]string{"Enabled", strconv.FormatBool(target.Enabled)},
+		[]string{"HandoutCname", InterfaceToStr(target.HandoutCname)},
+		[]string{"Servers", strings.Join(target.Servers, ", ")},
+		[]string{"Weight", FloatToStr(target.Weight)},
+	}
+
+	return data
+}
+
+// LivenessTest writes the details of a liveness test.
+func LivenessTest(w io.Writer, test edgegrid.LivenessTest) {
+	data := [][]string{
+		[]string{"Name", test.Name},
+		[]string{"HTTPError3xx", strconv.FormatBool(test.HTTPError3xx)},
+		[]string{"HTTPError4xx", strconv.FormatBool(test.HTTPError4xx)},
+		[]string{"HTTPError5xx", strconv.FormatBool(test.HTTPError5xx)},
+		[]string{"TestInterval", strconv.FormatInt(test.TestInterval, 10)},
+		[]string{"TestObject", test.TestObject},
+		[]string{"TestObjectPort", strconv.FormatInt(test.TestObjectPort, 10)},
+		[]string{"TestObjectProtocol", test.TestObjectProtocol},
+		[]string{"TestObjectUsername", test.TestObjectUsername},
+		[]string{"TestObjectPassword", test.TestObjectPassword},
+		[]string{"TestTimeout", FloatToStr(test.TestTimeout)},
+		[]string{"DisableNonstandardPortWarning", strconv.FormatBool(test.DisableNonstandardPortWarning)},
+		[]string{"RequestString", test.RequestString},
+		[]string{"ResponseString", test.ResponseString},
+		[]string{"SSLClientPrivateKey", test.SSLClientPrivateKey},
+		[]string{"SSLCertificate", test.SSLCertificate},
+		[]string{"HostHeader", test.HostHeader},
+	}
+
+	BasicTable(w, data)
+}
+
+// References writes the objects referring to a data center.
+func References(w io.Writer, refs []gtm.Reference) {
+	gtm.SortReferences(refs)
+
+	data := [][]string{}
+	for _, ref := range refs {
+		data = append(data, []string{ref.Type, ref.Name, ref.Detail})
+	}
+
+	TableWithHeaders(w, []string{"Type", "Name", "Detail"}, data)
+}
@@ -0,0 +1,371 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comcast/go-edgegrid/edgegrid"
+)
+
+// maskedSecret replaces secrets such as liveness test passwords in reports.
+const maskedSecret = "********"
+
+// ReportData is what a domain report describes.
+type ReportData struct {
+	Domain      *edgegrid.Domain
+	DataCenters []edgegrid.DataCenter
+	Properties  []edgegrid.Property
+	Generated   time.Time
+}
+
+// reportWriter writes the elements of a report in an output format.
+type reportWriter interface {
+	begin(title string)
+	heading(level int, text string)
+	paragraph(text string)
+	// fields writes name, value rows.
+	fields(rows [][]string)
+	table(headers []string, rows [][]string)
+	end()
+}
+
+// ReportMarkdown writes a report of a domain as Markdown.
+func ReportMarkdown(w io.Writer, data *ReportData) {
+	report(&markdownReport{w: w}, data)
+}
+
+// ReportHTML writes a report of a domain as a self-contained HTML page.
+func ReportHTML(w io.Writer, data *ReportData) {
+	report(&htmlReport{w: w}, data)
+}
+
+func report(w reportWriter, data *ReportData) {
+	domain := data.Domain
+	nicknames := map[int]string{}
+	targets := map[int]int{}
+	for _, dc := range data.DataCenters {
+		nicknames[dc.DataCenterID] = dc.Nickname
+	}
+	for _, prop := range data.Properties {
+		for _, t := range prop.TrafficTargets {
+			targets[t.DataCenterID]++
+		}
+	}
+
+	w.begin("GTM domain " + domain.Name)
+	w.heading(1, "GTM domain "+domain.Name)
+	w.paragraph(fmt.Sprintf("Generated by akamai-gtm at %s.", data.Generated.UTC().Format(time.RFC1123)))
+
+	w.heading(2, "Domain settings")
+	rows := [][]string{
+		{"Name", domain.Name},
+		{"Type", domain.Type},
+		{"Data centers", strconv.Itoa(len(data.DataCenters))},
+		{"Properties", strconv.Itoa(len(data.Properties))},
+	}
+	if status := domain.Status; status != nil {
+		rows = append(rows,
+			[]string{"Propagation status", status.PropagationStatus},
+			[]string{"Passing validation", yesNo(status.PassingValidation)},
+			[]string{"Change ID", status.ChangeID},
+		)
+	}
+	rows = append(rows,
+		[]string{"Last modified", domain.LastModified},
+		[]string{"Last modified by", domain.LastModifiedBy},
+		[]string{"Modification comments", domain.ModificationComments},
+	)
+	w.fields(nonEmptyRows(rows))
+
+	w.heading(2, "Data centers")
+	if len(data.DataCenters) == 0 {
+		w.paragraph("None.")
+	} else {
+		dcs := append([]edgegrid.DataCenter{}, data.DataCenters...)
+		sort.Slice(dcs, func(i, j int) bool { return dcs[i].DataCenterID < dcs[j].DataCenterID })
+		rows := [][]string{}
+		for _, dc := range dcs {
+			rows = append(rows, []string{
+				strconv.Itoa(dc.DataCenterID),
+				dc.Nickname,
+				joinNonEmpty(", ", dc.City, dc.StateOrProvince, dc.Country),
+				dc.Continent,
+				coordinates(dc),
+				yesNo(dc.Virtual),
+				strconv.Itoa(targets[dc.DataCenterID]),
+			})
+		}
+		w.table([]string{"ID", "Nickname", "Location", "Continent", "Coordinates", "Virtual", "Traffic targets"}, rows)
+	}
+
+	w.heading(2, "Properties")
+	if len(data.Properties) == 0 {
+		w.paragraph("None.")
+	}
+	props := append([]edgegrid.Property{}, data.Properties...)
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	for _, prop := range props {
+		reportProperty(w, prop, nicknames)
+	}
+
+	w.end()
+}
+
+func reportProperty(w reportWriter, prop edgegrid.Property, nicknames map[int]string) {
+	w.heading(3, prop.Name)
+	w.fields(nonEmptyRows([][]string{
+		{"Type", prop.Type},
+		{"Handout mode", prop.HandoutMode},
+		{"Score aggregation", prop.ScoreAggregationType},
+		{"Static TTL", reportValue(prop.StaticTTL)},
+		{"Dynamic TTL", reportNumber(float64(prop.DynamicTTL))},
+		{"Failover delay", strconv.Itoa(prop.FailoverDelay) + "s"},
+		{"Failback delay", strconv.Itoa(prop.FailbackDelay) + "s"},
+		{"Backup CNAME", prop.BackupCname},
+		{"Backup IP", prop.BackupIP},
+		{"CNAME", prop.Cname},
+		{"Health threshold", reportNumber(prop.HealthThreshold)},
+		{"Health multiplier", reportNumber(prop.HealthMultiplier)},
+		{"Health max", reportNumber(prop.HealthMax)},
+		{"Load imbalance percentage", reportNumber(prop.LoadImbalancePercentage)},
+		{"Unreachable threshold", reportValue(prop.UnreachableThreshold)},
+		{"Max unreachable penalty", reportValue(prop.MaxUnreachablePenalty)},
+		{"Map", reportValue(prop.MapName)},
+		{"IPv6", yesNo(prop.Ipv6)},
+		{"Comments", prop.Comments},
+		{"Last modified", prop.LastModified},
+	}))
+
+	w.heading(4, "Traffic targets")
+	if len(prop.TrafficTargets) == 0 {
+		w.paragraph("None.")
+	} else {
+		rows := [][]string{}
+		for _, t := range prop.TrafficTargets {
+			dc := strconv.Itoa(t.DataCenterID)
+			if name, ok := nicknames[t.DataCenterID]; ok {
+				dc = fmt.Sprintf("%s (%d)", name, t.DataCenterID)
+			}
+			rows = append(rows, []string{
+				dc,
+				yesNo(t.Enabled),
+				strconv.FormatFloat(t.Weight, 'f', -1, 64),
+				strings.Join(t.Servers, ", "),
+				reportValue(t.HandoutCname),
+			})
+		}
+		w.table([]string{"Data center", "Enabled", "Weight", "Servers", "Handout CNAME"}, rows)
+	}
+
+	w.heading(4, "Liveness tests")
+	if len(prop.LivenessTests) == 0 {
+		w.paragraph("None.")
+		return
+	}
+	for _, test := range prop.LivenessTests {
+		errors := []string{}
+		for _, e := range []struct {
+			class string
+			set   bool
+		}{{"3xx", test.HTTPError3xx}, {"4xx", test.HTTPError4xx}, {"5xx", test.HTTPError5xx}} {
+			if e.set {
+				errors = append(errors, e.class)
+			}
+		}
+		w.heading(5, test.Name)
+		w.fields(nonEmptyRows([][]string{
+			{"Protocol", test.TestObjectProtocol},
+			{"Port", reportNumber(float64(test.TestObjectPort))},
+			{"Test object", test.TestObject},
+			{"Host header", test.HostHeader},
+			{"Interval", seconds(float64(test.TestInterval))},
+			{"Timeout", seconds(test.TestTimeout)},
+			{"Failing HTTP responses", strings.Join(errors, ", ")},
+			{"Request", test.RequestString},
+			{"Expected response", test.ResponseString},
+			{"Username", test.TestObjectUsername},
+			{"Password", mask(test.TestObjectPassword)},
+			{"Client certificate", mask(test.SSLCertificate)},
+			{"Client private key", mask(test.SSLClientPrivateKey)},
+		}))
+	}
+}
+
+// nonEmptyRows drops the name, value rows without a value.
+func nonEmptyRows(rows [][]string) [][]string {
+	kept := [][]string{}
+	for _, row := range rows {
+		if row[1] != "" {
+			kept = append(kept, row)
+		}
+	}
+
+	return kept
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	kept := []string{}
+	for _, v := range values {
+		if v != "" {
+			kept = append(kept, v)
+		}
+	}
+
+	return strings.Join(kept, sep)
+}
+
+func coordinates(dc edgegrid.DataCenter) string {
+	if dc.Latitude == 0 && dc.Longitude == 0 {
+		return ""
+	}
+
+	return strconv.FormatFloat(dc.Latitude, 'f', -1, 64) + ", " + strconv.FormatFloat(dc.Longitude, 'f', -1, 64)
+}
+
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return maskedSecret
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
+
+// reportNumber formats a number without trailing zeros, or returns "" for
+// zero so that unset settings are left out.
+func reportNumber(f float64) string {
+	if f == 0 {
+		return ""
+	}
+
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func seconds(f float64) string {
+	if f == 0 {
+		return ""
+	}
+
+	return reportNumber(f) + "s"
+}
+
+// reportValue formats the untyped fields of GTM objects.
+func reportValue(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return reportNumber(f)
+	}
+
+	return InterfaceToStr(v)
+}
+
+// markdownReport writes a report as Markdown.
+type markdownReport struct {
+	w io.Writer
+}
+
+func (m *markdownReport) begin(title string) {}
+
+func (m *markdownReport) heading(level int, text string) {
+	fmt.Fprintf(m.w, "%s %s\n\n", strings.Repeat("#", level), text)
+}
+
+func (m *markdownReport) paragraph(text string) {
+	fmt.Fprintf(m.w, "%s\n\n", text)
+}
+
+func (m *markdownReport) fields(rows [][]string) {
+	m.table([]string{"Setting", "Value"}, rows)
+}
+
+func (m *markdownReport) table(headers []string, rows [][]string) {
+	line := func(cells []string) {
+		escaped := []string{}
+		for _, c := range cells {
+			c = strings.Replace(c, "|", `\|`, -1)
+			c = strings.Replace(c, "\r\n", "<br>", -1)
+			c = strings.Replace(c, "\n", "<br>", -1)
+			escaped = append(escaped, c)
+		}
+		fmt.Fprintf(m.w, "| %s |\n", strings.Join(escaped, " | "))
+	}
+	line(headers)
+	rule := []string{}
+	for range headers {
+		rule = append(rule, "---")
+	}
+	fmt.Fprintf(m.w, "|%s|\n", strings.Join(rule, "|"))
+	for _, row := range rows {
+		line(row)
+	}
+	fmt.Fprintln(m.w)
+}
+
+func (m *markdownReport) end() {}
+
+// htmlStyle is the style sheet embedded in HTML reports.
+const htmlStyle = `body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 70em; padding: 0 1em; color: #222; }
+h1, h2 { border-bottom: 1px solid #ddd; padding-bottom: .2em; }
+h3 { margin-top: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: .3em .6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+td { white-space: pre-wrap; }
+table.fields th { width: 14em; }`
+
+// htmlReport writes a report as a self-contained HTML page.
+type htmlReport struct {
+	w io.Writer
+}
+
+func (h *htmlReport) begin(title string) {
+	fmt.Fprintf(h.w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", html.EscapeString(title), htmlStyle)
+}
+
+func (h *htmlReport) heading(level int, text string) {
+	fmt.Fprintf(h.w, "<h%d>%s</h%d>\n", level, html.EscapeString(text), level)
+}
+
+func (h *htmlReport) paragraph(text string) {
+	fmt.Fprintf(h.w, "<p>%s</p>\n", html.EscapeString(text))
+}
+
+func (h *htmlReport) fields(rows [][]string) {
+	fmt.Fprintln(h.w, `<table class="fields">`)
+	for _, row := range rows {
+		fmt.Fprintf(h.w, "<tr><th>%s</th><td>%s</td></tr>\n", html.EscapeString(row[0]), html.EscapeString(row[1]))
+	}
+	fmt.Fprintln(h.w, "</table>")
+}
+
+func (h *htmlReport) table(headers []string, rows [][]string) {
+	fmt.Fprintln(h.w, "<table>")
+	fmt.Fprint(h.w, "<tr>")
+	for _, header := range headers {
+		fmt.Fprintf(h.w, "<th>%s</th>", html.EscapeString(header))
+	}
+	fmt.Fprintln(h.w, "</tr>")
+	for _, row := range rows {
+		fmt.Fprint(h.w, "<tr>")
+		for _, cell := range row {
+			fmt.Fprintf(h.w, "<td>%s</td>", html.EscapeString(cell))
+		}
+		fmt.Fprintln(h.w, "</tr>")
+	}
+	fmt.Fprintln(h.w, "</table>")
+}
+
+func (h *htmlReport) end() {
+	fmt.Fprintln(h.w, "</body>\n</html>")
+}
@@ -0,0 +1,25 @@
+// Package render formats GTM objects for display.
+package render
+
+import (
+	"io"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// BasicTable writes rows of cells as a table without headers.
+func BasicTable(w io.Writer, data [][]string) {
+	table := tablewriter.NewWriter(w)
+	table.AppendBulk(data)
+	table.SetRowLine(true)
+	table.Render()
+}
+
+// TableWithHeaders writes rows of cells as a table with headers.
+func TableWithHeaders(w io.Writer, headers []string, data [][]string) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(headers)
+	table.AppendBulk(data)
+	table.SetRowLine(true)
+	table.Render()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/urfave/cli"
+)
+
+func report(c *cli.Context) error {
+	var write func(io.Writer, *render.ReportData)
+	switch format := c.String("format"); format {
+	case "markdown", "md":
+		write = render.ReportMarkdown
+	case "html":
+		write = render.ReportHTML
+	default:
+		return fmt.Errorf("unknown report format %q: expected markdown or html", format)
+	}
+
+	var exp *config.Export
+	var err error
+	if dir := c.String("dir"); dir != "" {
+		exp, err = config.LoadExport(dir)
+	} else {
+		exp, err = config.FetchExport(client(c), c.Args().First())
+	}
+	if err != nil {
+		return err
+	}
+
+	data := &render.ReportData{
+		Domain:      exp.Domain,
+		DataCenters: exp.DataCenters,
+		Properties:  exp.Properties,
+		Generated:   time.Now(),
+	}
+
+	output := c.String("output")
+	if output == "" {
+		write(os.Stdout, data)
+		return nil
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	write(f, data)
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", output)
+
+	return nil
+}
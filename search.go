@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// searchHit is a single property field matching a search.
+type searchHit struct {
+	Domain   string
+	Property string
+	Field    string
+	Value    string
+}
+
+// searchMatcher reports whether a value matches the search term.
+type searchMatcher func(value string) bool
+
+func search(c *cli.Context) error {
+	term := c.Args().First()
+	if term == "" {
+		return fmt.Errorf("a search term is required")
+	}
+	match, err := newSearchMatcher(term, c.Bool("exact"), c.Bool("regexp"))
+	if err != nil {
+		return err
+	}
+
+	client := client(c)
+	domainNames := c.StringSlice("domain")
+	if len(domainNames) == 0 {
+		domains, err := client.Domains()
+		if err != nil {
+			return err
+		}
+		for _, domain := range domains {
+			domainNames = append(domainNames, domain.Name)
+		}
+	}
+
+	hits := []searchHit{}
+	for _, domain := range domainNames {
+		dcs, err := client.DataCenters(domain)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to search DataCenters of Domain: %s\nError is: %v\n", domain, err)
+			continue
+		}
+		props, err := client.Properties(domain)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to search Properties of Domain: %s\nError is: %v\n", domain, err)
+			continue
+		}
+		hits = append(hits, searchProperties(domain, props.Properties, dcs, match)...)
+	}
+
+	if len(hits) == 0 {
+		fmt.Printf("No matches found for: %s\n", term)
+		return nil
+	}
+
+	data := [][]string{}
+	for _, hit := range hits {
+		data = append(data, []string{hit.Domain, hit.Property, hit.Field, hit.Value})
+	}
+	render.TableWithHeaders(os.Stdout, []string{"Domain", "Property", "Field", "Value"}, data)
+
+	return nil
+}
+
+func newSearchMatcher(term string, exact, isRegexp bool) (searchMatcher, error) {
+	switch {
+	case isRegexp:
+		re, err := regexp.Compile(term)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	case exact:
+		return func(value string) bool {
+			return strings.EqualFold(value, term)
+		}, nil
+	default:
+		lower := strings.ToLower(term)
+		return func(value string) bool {
+			return strings.Contains(strings.ToLower(value), lower)
+		}, nil
+	}
+}
+
+// searchProperties returns the hits within the properties of a domain.
+// Traffic targets also match on the nickname and city of their data center.
+func searchProperties(domain string, props []edgegrid.Property, dcs []edgegrid.DataCenter, match searchMatcher) []searchHit {
+	dcsByID := map[int]edgegrid.DataCenter{}
+	for _, dc := range dcs {
+		dcsByID[dc.DataCenterID] = dc
+	}
+
+	hits := []searchHit{}
+	for _, prop := range props {
+		add := func(field, value string) {
+			if value != "" && match(value) {
+				hits = append(hits, searchHit{domain, prop.Name, field, value})
+			}
+		}
+
+		add("BackupIP", prop.BackupIP)
+		add("BackupCname", prop.BackupCname)
+
+		for i, target := range prop.TrafficTargets {
+			prefix := fmt.Sprintf("TrafficTargets[%d].", i)
+			for _, server := range target.Servers {
+				add(prefix+"Servers", server)
+			}
+			add(prefix+"HandoutCname", render.InterfaceToStr(target.HandoutCname))
+			if dc, ok := dcsByID[target.DataCenterID]; ok {
+				id := strconv.Itoa(dc.DataCenterID)
+				if match(dc.Nickname) {
+					hits = append(hits, searchHit{domain, prop.Name, prefix + "DataCenter.Nickname", dc.Nickname + " (" + id + ")"})
+				}
+				if dc.City != "" && match(dc.City) {
+					hits = append(hits, searchHit{domain, prop.Name, prefix + "DataCenter.City", dc.City + " (" + id + ")"})
+				}
+			}
+		}
+
+		for i, test := range prop.LivenessTests {
+			prefix := fmt.Sprintf("LivenessTests[%d].", i)
+			add(prefix+"TestObject", test.TestObject)
+			add(prefix+"HostHeader", test.HostHeader)
+		}
+	}
+
+	return hits
+}
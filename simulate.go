@@ -0,0 +1,494 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// maxNormalHandout is the number of addresses handed out in the normal
+// handout mode.
+const maxNormalHandout = 8
+
+// defaultLoadImbalancePercentage is used by performance properties that do
+// not set LoadImbalancePercentage.
+const defaultLoadImbalancePercentage = 10
+
+// simState is the assumed state of the world for a simulation.
+type simState struct {
+	// Down holds the IDs of data centers assumed to be down.
+	Down map[int]bool
+	// DownServers holds individual servers assumed to be down.
+	DownServers map[string]bool
+	// Client identifies the resolver for hashed and persistent handouts.
+	Client string
+	// Closest orders data centers by proximity to the client, for
+	// performance properties.
+	Closest []int
+	// MapDC is the data center a mapping property's map assigns the
+	// client to.
+	MapDC int
+	// Load is the current share of traffic (in percent) served by each
+	// data center, for load imbalance decisions.
+	Load map[int]float64
+}
+
+// simAnswer is a possible answer and the probability of it being returned.
+type simAnswer struct {
+	DataCenter  int
+	Probability float64
+	Type        string
+	Records     []string
+}
+
+// simResult is the outcome of a simulation.
+type simResult struct {
+	Answers []simAnswer
+	Notes   []string
+}
+
+func (r *simResult) note(format string, args ...interface{}) {
+	r.Notes = append(r.Notes, fmt.Sprintf(format, args...))
+}
+
+// simulateFlags select the property to simulate and the assumed state.
+var simulateFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "name",
+		Usage: "The Property name, to simulate the live Property",
+	},
+	cli.StringFlag{
+		Name:  "file, json",
+		Usage: "The path to a YAML or JSON Property file, or - for stdin, to simulate instead of the live Property",
+	},
+	cli.IntSliceFlag{
+		Name:  "down",
+		Usage: "A data center ID to assume is down; may be repeated",
+	},
+	cli.StringSliceFlag{
+		Name:  "down-server",
+		Usage: "A server to assume is down; may be repeated",
+	},
+	cli.StringFlag{
+		Name:  "client",
+		Usage: "The resolver address, for weighted-hashed properties and persistent handouts",
+	},
+	cli.IntSliceFlag{
+		Name:  "closest",
+		Usage: "Data center IDs in order of proximity to the client, for performance properties; may be repeated",
+	},
+	cli.IntFlag{
+		Name:  "map-dc",
+		Usage: "The data center ID the map assigns the client to, for mapping properties",
+	},
+	cli.StringSliceFlag{
+		Name:  "load",
+		Usage: "The share of traffic currently served by a data center as <dataCenterId>=<percent>; may be repeated",
+	},
+}
+
+func simulate(c *cli.Context) error {
+	prop, err := simulatedProperty(c)
+	if err != nil {
+		return err
+	}
+	state, err := simulationState(c)
+	if err != nil {
+		return err
+	}
+	names, err := dataCenterNames(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := simulateProperty(prop, state)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Property %s (%s), handout mode %s\n", prop.Name, prop.Type, handoutMode(prop))
+
+	data := [][]string{}
+	for _, answer := range result.Answers {
+		data = append(data, []string{
+			dcLabel(answer.DataCenter, names),
+			fmt.Sprintf("%.2f%%", answer.Probability*100),
+			answer.Type,
+			strings.Join(answer.Records, ", "),
+		})
+	}
+	render.TableWithHeaders(os.Stdout, []string{"DataCenter", "Probability", "Type", "Answer"}, data)
+
+	for _, note := range result.Notes {
+		fmt.Printf("* %s\n", note)
+	}
+
+	return nil
+}
+
+// simulatedProperty returns the property given by --file, or the live
+// property given by --name.
+func simulatedProperty(c *cli.Context) (*edgegrid.Property, error) {
+	if c.String("file") != "" {
+		prop := &edgegrid.Property{}
+		if err := unmarshalInput(c, prop); err != nil {
+			return nil, err
+		}
+		return prop, nil
+	}
+	if c.String("name") == "" {
+		return nil, fmt.Errorf("one of --name or --file is required")
+	}
+
+	return client(c).Property(c.Args().First(), c.String("name"))
+}
+
+func simulationState(c *cli.Context) (simState, error) {
+	state := simState{
+		Down:        map[int]bool{},
+		DownServers: map[string]bool{},
+		Client:      c.String("client"),
+		Closest:     c.IntSlice("closest"),
+		MapDC:       c.Int("map-dc"),
+		Load:        map[int]float64{},
+	}
+	for _, id := range c.IntSlice("down") {
+		state.Down[id] = true
+	}
+	for _, server := range c.StringSlice("down-server") {
+		state.DownServers[server] = true
+	}
+	for _, load := range c.StringSlice("load") {
+		kv := strings.SplitN(load, "=", 2)
+		if len(kv) != 2 {
+			return state, fmt.Errorf("invalid --load %q: expected <dataCenterId>=<percent>", load)
+		}
+		id, err := strconv.Atoi(kv[0])
+		if err != nil {
+			return state, fmt.Errorf("invalid --load %q: %v", load, err)
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(kv[1], "%"), 64)
+		if err != nil {
+			return state, fmt.Errorf("invalid --load %q: %v", load, err)
+		}
+		state.Load[id] = percent
+	}
+
+	return state, nil
+}
+
+// dataCenterNames returns the nicknames of the data centers of the domain
+// given as the first argument, if any.
+func dataCenterNames(c *cli.Context) (map[int]string, error) {
+	names := map[int]string{}
+	domain := c.Args().First()
+	if domain == "" {
+		return names, nil
+	}
+	dcs, err := client(c).DataCenters(domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, dc := range dcs {
+		names[dc.DataCenterID] = dc.Nickname
+	}
+
+	return names, nil
+}
+
+func dcLabel(id int, names map[int]string) string {
+	if id == 0 {
+		return "backup"
+	}
+	if name := names[id]; name != "" {
+		return fmt.Sprintf("%s (%d)", name, id)
+	}
+
+	return strconv.Itoa(id)
+}
+
+func handoutMode(prop *edgegrid.Property) string {
+	if prop.HandoutMode == "" {
+		return "normal"
+	}
+
+	return prop.HandoutMode
+}
+
+// simulateProperty computes the answers GTM would hand out for prop in the
+// given state.
+func simulateProperty(prop *edgegrid.Property, state simState) (simResult, error) {
+	result := simResult{}
+
+	live := liveTargets(prop, state)
+	if len(live) == 0 {
+		switch {
+		case prop.BackupCname != "":
+			result.Answers = []simAnswer{{Probability: 1, Type: "CNAME", Records: []string{prop.BackupCname}}}
+			result.note("No traffic target is live; the backup CNAME is handed out")
+			return result, nil
+		case prop.BackupIP != "":
+			result.Answers = []simAnswer{{Probability: 1, Type: recordType(prop), Records: []string{prop.BackupIP}}}
+			result.note("No traffic target is live; the backup IP is handed out")
+			return result, nil
+		}
+		result.note("No traffic target is live and there is no backup; all enabled targets are treated as live")
+		state = simState{Client: state.Client, Closest: state.Closest, MapDC: state.MapDC, Load: state.Load}
+		live = liveTargets(prop, state)
+		if len(live) == 0 {
+			return result, fmt.Errorf("property %s has no enabled traffic targets with servers", prop.Name)
+		}
+	}
+
+	shares, err := targetShares(prop, live, state, &result)
+	if err != nil {
+		return result, err
+	}
+
+	for _, target := range live {
+		share := shares[target.DataCenterID]
+		if share == 0 {
+			continue
+		}
+		for _, answer := range handout(prop, target, state) {
+			answer.Probability *= share
+			result.Answers = append(result.Answers, answer)
+		}
+	}
+
+	if down := downTargets(prop, state); len(down) != 0 && prop.FailoverDelay != 0 {
+		result.note("Traffic moves away from down data centers (%s) only after FailoverDelay (%ds)", strings.Join(down, ", "), prop.FailoverDelay)
+	}
+
+	return result, nil
+}
+
+// targetShares returns the fraction of answers handed out from each live
+// target, keyed by data center ID.
+func targetShares(prop *edgegrid.Property, live []edgegrid.TrafficTarget, state simState, result *simResult) (map[int]float64, error) {
+	shares := map[int]float64{}
+
+	switch prop.Type {
+	case "failover":
+		best := live[0]
+		for _, target := range live[1:] {
+			if target.Weight > best.Weight {
+				best = target
+			}
+		}
+		shares[best.DataCenterID] = 1
+		result.note("Failover hands out the live target with the highest weight")
+
+	case "weighted-round-robin", "weighted-round-robin-load-feedback", "weighted-hashed":
+		for id, share := range weightShares(live) {
+			shares[id] = share
+		}
+		if prop.Type == "weighted-hashed" && state.Client != "" {
+			id := hashedTarget(live, shares, state.Client)
+			shares = map[int]float64{id: 1}
+			result.note("Client %s is consistently hashed to data center %d", state.Client, id)
+		}
+
+	case "performance":
+		order := performanceOrder(live, state.Closest)
+		chosen := order[0]
+		if len(state.Load) != 0 {
+			lip := prop.LoadImbalancePercentage
+			if lip == 0 {
+				lip = defaultLoadImbalancePercentage
+			}
+			total := 0.0
+			for _, target := range live {
+				total += state.Load[target.DataCenterID]
+			}
+			limit := total / float64(len(live)) * (1 + lip/100)
+			for _, target := range order {
+				if state.Load[target.DataCenterID] <= limit {
+					chosen = target
+					break
+				}
+				result.note("Data center %d is skipped: load %.1f%% exceeds %.1f%% (LoadImbalancePercentage %.0f%%)", target.DataCenterID, state.Load[target.DataCenterID], limit, lip)
+			}
+		}
+		shares[chosen.DataCenterID] = 1
+		if len(state.Closest) == 0 {
+			result.note("Performance answers depend on the client; pass --closest to order data centers by proximity")
+		}
+
+	case "geographic", "cidrmapping", "asmapping":
+		if state.MapDC == 0 {
+			return nil, fmt.Errorf("%s properties require --map-dc, the data center map %s assigns the client to", prop.Type, render.InterfaceToStr(prop.MapName))
+		}
+		for _, target := range live {
+			if target.DataCenterID == state.MapDC {
+				shares[target.DataCenterID] = 1
+				return shares, nil
+			}
+		}
+		return nil, fmt.Errorf("data center %d assigned by the map has no live traffic target", state.MapDC)
+
+	default:
+		return nil, fmt.Errorf("unsupported property type %q", prop.Type)
+	}
+
+	return shares, nil
+}
+
+// weightShares normalises the weights of targets to fractions of 1. If no
+// target has a positive weight, the targets share equally.
+func weightShares(targets []edgegrid.TrafficTarget) map[int]float64 {
+	shares := map[int]float64{}
+	total := 0.0
+	for _, target := range targets {
+		if target.Weight > 0 {
+			total += target.Weight
+		}
+	}
+	for _, target := range targets {
+		switch {
+		case total == 0:
+			shares[target.DataCenterID] += 1 / float64(len(targets))
+		case target.Weight > 0:
+			shares[target.DataCenterID] += target.Weight / total
+		}
+	}
+
+	return shares
+}
+
+// hashedTarget picks the data center a client is hashed to, in proportion
+// to the shares of the targets.
+func hashedTarget(targets []edgegrid.TrafficTarget, shares map[int]float64, client string) int {
+	point := float64(hash(client)%10000) / 10000
+	cumulative := 0.0
+	for _, target := range targets {
+		cumulative += shares[target.DataCenterID]
+		if point < cumulative {
+			return target.DataCenterID
+		}
+	}
+
+	return targets[len(targets)-1].DataCenterID
+}
+
+func performanceOrder(live []edgegrid.TrafficTarget, closest []int) []edgegrid.TrafficTarget {
+	rank := map[int]int{}
+	for i, id := range closest {
+		if _, ok := rank[id]; !ok {
+			rank[id] = i
+		}
+	}
+	order := append([]edgegrid.TrafficTarget{}, live...)
+	sort.SliceStable(order, func(i, j int) bool {
+		ri, iok := rank[order[i].DataCenterID]
+		rj, jok := rank[order[j].DataCenterID]
+		if iok != jok {
+			return iok
+		}
+		return ri < rj
+	})
+
+	return order
+}
+
+// liveTargets returns the enabled targets whose data center is up and which
+// have a live server or hand out a CNAME.
+func liveTargets(prop *edgegrid.Property, state simState) []edgegrid.TrafficTarget {
+	live := []edgegrid.TrafficTarget{}
+	for _, target := range prop.TrafficTargets {
+		if !target.Enabled || state.Down[target.DataCenterID] {
+			continue
+		}
+		if render.InterfaceToStr(target.HandoutCname) != "" || len(liveServers(target, state)) != 0 {
+			live = append(live, target)
+		}
+	}
+
+	return live
+}
+
+func downTargets(prop *edgegrid.Property, state simState) []string {
+	down := []string{}
+	for _, target := range prop.TrafficTargets {
+		if target.Enabled && state.Down[target.DataCenterID] {
+			down = append(down, strconv.Itoa(target.DataCenterID))
+		}
+	}
+
+	return down
+}
+
+func liveServers(target edgegrid.TrafficTarget, state simState) []string {
+	servers := []string{}
+	for _, server := range target.Servers {
+		if !state.DownServers[server] {
+			servers = append(servers, server)
+		}
+	}
+
+	return servers
+}
+
+func recordType(prop *edgegrid.Property) string {
+	if prop.Ipv6 {
+		return "AAAA"
+	}
+
+	return "A"
+}
+
+// handout returns the answers handed out from a target according to the
+// property's handout mode, with probabilities relative to the target.
+func handout(prop *edgegrid.Property, target edgegrid.TrafficTarget, state simState) []simAnswer {
+	id := target.DataCenterID
+	if cname := render.InterfaceToStr(target.HandoutCname); cname != "" {
+		return []simAnswer{{DataCenter: id, Probability: 1, Type: "CNAME", Records: []string{cname}}}
+	}
+
+	servers := liveServers(target, state)
+	typ := recordType(prop)
+	single := func() []simAnswer {
+		if state.Client != "" {
+			server := servers[hash(state.Client)%uint32(len(servers))]
+			return []simAnswer{{DataCenter: id, Probability: 1, Type: typ, Records: []string{server}}}
+		}
+		answers := []simAnswer{}
+		for _, server := range servers {
+			answers = append(answers, simAnswer{DataCenter: id, Probability: 1 / float64(len(servers)), Type: typ, Records: []string{server}})
+		}
+		return answers
+	}
+
+	switch handoutMode(prop) {
+	case "all-live-ips":
+		return []simAnswer{{DataCenter: id, Probability: 1, Type: typ, Records: servers}}
+	case "one-ip":
+		// one address, rotated between queries
+		answers := []simAnswer{}
+		for _, server := range servers {
+			answers = append(answers, simAnswer{DataCenter: id, Probability: 1 / float64(len(servers)), Type: typ, Records: []string{server}})
+		}
+		return answers
+	case "persistent", "one-ip-hashed":
+		return single()
+	default:
+		if len(servers) > maxNormalHandout {
+			servers = servers[:maxNormalHandout]
+		}
+		return []simAnswer{{DataCenter: id, Probability: 1, Type: typ, Records: servers}}
+	}
+}
+
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+
+	return h.Sum32()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+)
+
+// stateDir returns the directory akamai-gtm keeps local state in: the
+// --state-dir flag, or ~/.akamai-gtm.
+func stateDir(c *cli.Context) string {
+	if dir := c.GlobalString("state-dir"); dir != "" {
+		return dir
+	}
+	home := os.Getenv("HOME")
+	if u, err := user.Current(); home == "" && err == nil {
+		home = u.HomeDir
+	}
+
+	return filepath.Join(home, ".akamai-gtm")
+}
+
+// basePath is where the version of an object that an export or edit was
+// based on is kept, so that updates made from it can be merged with
+// changes made since.
+func basePath(c *cli.Context, domain, objectType, name, version string) string {
+	return filepath.Join(stateDir(c), "bases", domain, objectType, url.PathEscape(name), url.QueryEscape(version)+".json")
+}
+
+// saveBase keeps a copy of obj as the base of later updates. Objects
+// without a version, such as data centers, are not kept.
+func saveBase(c *cli.Context, domain, objectType, name string, obj interface{}) error {
+	version := objectVersion(obj)
+	if version == "" {
+		return nil
+	}
+	path := basePath(c, domain, objectType, name, version)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// loadBase reads the kept version of an object into obj, reporting whether
+// there was one.
+func loadBase(c *cli.Context, domain, objectType, name, version string, obj interface{}) (bool, error) {
+	data, err := ioutil.ReadFile(basePath(c, domain, objectType, name, version))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, json.Unmarshal(data, obj)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/comcast/akamai-gtm/config"
+	"github.com/urfave/cli"
+)
+
+// templateFlags are shared by every command that reads an input file,
+// allowing the file to be rendered as a Go text/template before use.
+var templateFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "vars",
+		Usage: "The path to a YAML or JSON file of template variables; may be repeated",
+	},
+	cli.StringSliceFlag{
+		Name:  "set",
+		Usage: "A template variable as key=value; may be repeated and overrides --vars",
+	},
+}
+
+func renderTemplate(c *cli.Context) error {
+	data, _, err := renderInput(c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// renderInput reads the file named by --file and renders it as a template
+// using the variables supplied by --vars and --set, returning the result
+// and its detected format. Referencing a variable that has not been
+// supplied is an error.
+func renderInput(c *cli.Context) ([]byte, config.Format, error) {
+	data, err := config.ReadFile(c.String("file"))
+	if err != nil {
+		return nil, config.FormatJSON, err
+	}
+	vars, err := config.TemplateVars(c.StringSlice("vars"), c.StringSlice("set"))
+	if err != nil {
+		return nil, config.FormatJSON, err
+	}
+	data, err = config.RenderTemplate(inputName(c), data, vars)
+	if err != nil {
+		return nil, config.FormatJSON, err
+	}
+
+	return data, config.DetectFormat(c.String("file"), data), nil
+}
@@ -0,0 +1,123 @@
+package term
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// KeyCode identifies a key that does not produce a printable character.
+type KeyCode int
+
+// key codes
+const (
+	KeyNone KeyCode = iota
+	KeyRune
+	KeyCtrl
+	KeyEnter
+	KeyEsc
+	KeyTab
+	KeyBacktab
+	KeyBackspace
+	KeyDelete
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPgUp
+	KeyPgDn
+)
+
+// Key is a key press. Rune holds the character for KeyRune and the
+// lower-case letter for KeyCtrl.
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// Is reports whether k is the printable character r.
+func (k Key) Is(r rune) bool {
+	return k.Code == KeyRune && k.Rune == r
+}
+
+// IsCtrl reports whether k is Ctrl and the letter r.
+func (k Key) IsCtrl(r rune) bool {
+	return k.Code == KeyCtrl && k.Rune == r
+}
+
+// csiKeys maps the final byte of a CSI or SS3 sequence to a key.
+var csiKeys = map[byte]KeyCode{
+	'A': KeyUp,
+	'B': KeyDown,
+	'C': KeyRight,
+	'D': KeyLeft,
+	'H': KeyHome,
+	'F': KeyEnd,
+	'Z': KeyBacktab,
+}
+
+// tildeKeys maps the parameter of a CSI <n> ~ sequence to a key.
+var tildeKeys = map[string]KeyCode{
+	"1": KeyHome,
+	"3": KeyDelete,
+	"4": KeyEnd,
+	"5": KeyPgUp,
+	"6": KeyPgDn,
+	"7": KeyHome,
+	"8": KeyEnd,
+}
+
+// ParseKey decodes the first key in p and returns it with the number of
+// bytes it used. Unknown escape sequences are consumed and reported as
+// KeyNone.
+func ParseKey(p []byte) (Key, int) {
+	if len(p) == 0 {
+		return Key{}, 0
+	}
+
+	switch b := p[0]; {
+	case b == 0x1b:
+		return parseEscape(p)
+	case b == '\r' || b == '\n':
+		return Key{Code: KeyEnter}, 1
+	case b == '\t':
+		return Key{Code: KeyTab}, 1
+	case b == 0x7f || b == 0x08:
+		return Key{Code: KeyBackspace}, 1
+	case b >= 1 && b <= 26:
+		return Key{Code: KeyCtrl, Rune: rune('a' + b - 1)}, 1
+	case b < 0x20:
+		return Key{}, 1
+	}
+
+	r, n := utf8.DecodeRune(p)
+
+	return Key{Code: KeyRune, Rune: r}, n
+}
+
+func parseEscape(p []byte) (Key, int) {
+	if len(p) < 3 || (p[1] != '[' && p[1] != 'O') {
+		return Key{Code: KeyEsc}, 1
+	}
+
+	// parameters are digits and semicolons, up to the final byte
+	i := 2
+	for i < len(p) && (p[i] >= '0' && p[i] <= '9' || p[i] == ';') {
+		i++
+	}
+	if i == len(p) {
+		return Key{}, len(p)
+	}
+	params := string(p[2:i])
+	final := p[i]
+
+	if final == '~' {
+		if j := strings.IndexByte(params, ';'); j >= 0 {
+			params = params[:j]
+		}
+		return Key{Code: tildeKeys[params]}, i + 1
+	}
+
+	return Key{Code: csiKeys[final]}, i + 1
+}
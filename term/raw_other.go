@@ -0,0 +1,33 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package term
+
+import "errors"
+
+var errUnsupported = errors.New("terminal mode is not supported on this platform")
+
+type state struct{}
+
+// IsTerminal reports whether fd is a terminal. It is always false on
+// platforms without termios.
+func IsTerminal(fd int) bool {
+	return false
+}
+
+// Size returns the width and height of the terminal fd.
+func Size(fd int) (int, int, error) {
+	return 0, 0, errUnsupported
+}
+
+func makeRaw(fd int) (*state, error) {
+	return nil, errUnsupported
+}
+
+func restore(fd int, s *state) error {
+	return errUnsupported
+}
+
+func read(fd int, p []byte) (int, error) {
+	return 0, errUnsupported
+}
@@ -0,0 +1,66 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package term
+
+import "golang.org/x/sys/unix"
+
+type state struct {
+	termios unix.Termios
+}
+
+// IsTerminal reports whether fd is a terminal.
+func IsTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	return err == nil
+}
+
+// Size returns the width and height of the terminal fd.
+func Size(fd int) (int, int, error) {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int(ws.Col), int(ws.Row), nil
+}
+
+// makeRaw puts fd into raw mode, as cfmakeraw(3) does, except that reads
+// time out after a tenth of a second so the caller is never blocked for
+// long.
+func makeRaw(fd int) (*state, error) {
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+	saved := &state{termios: *termios}
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 0
+	termios.Cc[unix.VTIME] = 1
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+func restore(fd int, s *state) error {
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, &s.termios)
+}
+
+func read(fd int, p []byte) (int, error) {
+	n, err := unix.Read(fd, p)
+	if err == unix.EINTR || err == unix.EAGAIN {
+		return 0, nil
+	}
+	if n < 0 {
+		n = 0
+	}
+
+	return n, err
+}
@@ -0,0 +1,187 @@
+package term
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Style is the colour and attributes of a cell.
+type Style int
+
+// styles
+const (
+	StyleNormal Style = iota
+	StyleBold
+	StyleDim
+	StyleReverse
+	StyleRed
+	StyleGreen
+	StyleYellow
+	StyleCyan
+	StyleTitle
+)
+
+// sgr holds the SGR escape sequence of each style.
+var sgr = map[Style]string{
+	StyleNormal:  "\x1b[0m",
+	StyleBold:    "\x1b[0;1m",
+	StyleDim:     "\x1b[0;2m",
+	StyleReverse: "\x1b[0;7m",
+	StyleRed:     "\x1b[0;31m",
+	StyleGreen:   "\x1b[0;32m",
+	StyleYellow:  "\x1b[0;33m",
+	StyleCyan:    "\x1b[0;36m",
+	StyleTitle:   "\x1b[0;1;37;44m",
+}
+
+type cell struct {
+	r     rune
+	style Style
+}
+
+// Screen is an off-screen buffer of styled cells. Draw into it and call
+// Flush to update the terminal; only rows that changed since the last
+// Flush are rewritten.
+type Screen struct {
+	width, height int
+	cells         [][]cell
+	drawn         []string
+}
+
+// NewScreen returns a blank screen of the given size.
+func NewScreen(width, height int) *Screen {
+	s := &Screen{}
+	s.Resize(width, height)
+
+	return s
+}
+
+// Size returns the width and height of the screen.
+func (s *Screen) Size() (int, int) {
+	return s.width, s.height
+}
+
+// Resize changes the size of the screen, clearing it and forcing the next
+// Flush to redraw every row.
+func (s *Screen) Resize(width, height int) {
+	s.width, s.height = width, height
+	s.cells = make([][]cell, height)
+	for y := range s.cells {
+		s.cells[y] = make([]cell, width)
+	}
+	s.drawn = make([]string, height)
+	s.Clear()
+}
+
+// Clear blanks every cell.
+func (s *Screen) Clear() {
+	for y := range s.cells {
+		for x := range s.cells[y] {
+			s.cells[y][x] = cell{r: ' '}
+		}
+	}
+}
+
+// Set sets a single cell, ignoring positions off the screen.
+func (s *Screen) Set(x, y int, r rune, style Style) {
+	if x < 0 || y < 0 || x >= s.width || y >= s.height {
+		return
+	}
+	if unicode.IsControl(r) {
+		r = ' '
+	}
+	s.cells[y][x] = cell{r: r, style: style}
+}
+
+// Text writes text at x, y, cut off after width cells, and returns the
+// number of cells written.
+func (s *Screen) Text(x, y, width int, text string, style Style) int {
+	n := 0
+	for _, r := range text {
+		if n >= width {
+			break
+		}
+		s.Set(x+n, y, r, style)
+		n++
+	}
+
+	return n
+}
+
+// Line writes text at x, y and pads it with spaces to width cells, e.g. to
+// highlight a whole row.
+func (s *Screen) Line(x, y, width int, text string, style Style) {
+	for n := s.Text(x, y, width, text, style); n < width; n++ {
+		s.Set(x+n, y, ' ', style)
+	}
+}
+
+// Box draws a border around the rectangle x, y, width, height with title
+// in its top edge.
+func (s *Screen) Box(x, y, width, height int, title string, style Style) {
+	if width < 2 || height < 2 {
+		return
+	}
+	right, bottom := x+width-1, y+height-1
+	for i := x + 1; i < right; i++ {
+		s.Set(i, y, '─', style)
+		s.Set(i, bottom, '─', style)
+	}
+	for j := y + 1; j < bottom; j++ {
+		s.Set(x, j, '│', style)
+		s.Set(right, j, '│', style)
+	}
+	s.Set(x, y, '┌', style)
+	s.Set(right, y, '┐', style)
+	s.Set(x, bottom, '└', style)
+	s.Set(right, bottom, '┘', style)
+	if title != "" {
+		s.Text(x+2, y, width-4, " "+title+" ", style)
+	}
+}
+
+// Fill clears the rectangle x, y, width, height.
+func (s *Screen) Fill(x, y, width, height int, style Style) {
+	for j := y; j < y+height; j++ {
+		for i := x; i < x+width; i++ {
+			s.Set(i, j, ' ', style)
+		}
+	}
+}
+
+// Flush writes the rows that changed since the last Flush to w.
+func (s *Screen) Flush(w io.Writer) error {
+	out := &bytes.Buffer{}
+	for y, row := range s.cells {
+		line := renderRow(row)
+		if line == s.drawn[y] {
+			continue
+		}
+		fmt.Fprintf(out, "\x1b[%d;1H%s", y+1, line)
+		s.drawn[y] = line
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	_, err := w.Write(out.Bytes())
+
+	return err
+}
+
+func renderRow(row []cell) string {
+	b := &strings.Builder{}
+	style := Style(-1)
+	for _, c := range row {
+		if c.style != style {
+			b.WriteString(sgr[c.style])
+			style = c.style
+		}
+		b.WriteRune(c.r)
+	}
+	b.WriteString(sgr[StyleNormal])
+
+	return b.String()
+}
@@ -0,0 +1,112 @@
+// Package term puts a terminal into raw mode, reads keys from it and draws
+// full-screen interfaces with ANSI escape sequences.
+package term
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNotTerminal is returned by Open when stdin or stdout is not a
+// terminal.
+var ErrNotTerminal = errors.New("stdin and stdout must be a terminal")
+
+// ANSI sequences to switch to the alternate screen and hide the cursor, and
+// back.
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l\x1b[H\x1b[2J"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+)
+
+// Terminal is a terminal in raw mode showing the alternate screen.
+type Terminal struct {
+	in, out int
+	saved   *state
+	pending []byte
+	buf     []byte
+}
+
+// Open puts the terminal on stdin and stdout into raw mode and switches to
+// the alternate screen. Close restores it.
+func Open() (*Terminal, error) {
+	t := &Terminal{
+		in:  int(os.Stdin.Fd()),
+		out: int(os.Stdout.Fd()),
+		buf: make([]byte, 64),
+	}
+	if !IsTerminal(t.in) || !IsTerminal(t.out) {
+		return nil, ErrNotTerminal
+	}
+	if err := t.Resume(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Close restores the terminal to the state it was in before Open.
+func (t *Terminal) Close() error {
+	return t.Suspend()
+}
+
+// Suspend leaves the alternate screen and restores the terminal's original
+// mode, e.g. to run an editor. Resume undoes it.
+func (t *Terminal) Suspend() error {
+	if t.saved == nil {
+		return nil
+	}
+	fmt.Fprint(os.Stdout, leaveScreen)
+	err := restore(t.in, t.saved)
+	t.saved = nil
+
+	return err
+}
+
+// Resume puts the terminal back into raw mode and redraws from a blank
+// alternate screen.
+func (t *Terminal) Resume() error {
+	if t.saved != nil {
+		return nil
+	}
+	saved, err := makeRaw(t.in)
+	if err != nil {
+		return err
+	}
+	t.saved = saved
+	t.pending = nil
+	fmt.Fprint(os.Stdout, enterScreen)
+
+	return nil
+}
+
+// Size returns the width and height of the terminal.
+func (t *Terminal) Size() (int, int, error) {
+	return Size(t.out)
+}
+
+// ReadKey returns the next key pressed. It waits at most a tenth of a
+// second and returns a key with code KeyNone if nothing was pressed, so
+// callers can poll for other events such as a change of size.
+func (t *Terminal) ReadKey() (Key, error) {
+	if len(t.pending) == 0 {
+		n, err := read(t.in, t.buf)
+		if err != nil {
+			return Key{}, err
+		}
+		t.pending = append(t.pending, t.buf[:n]...)
+	}
+	if len(t.pending) == 0 {
+		return Key{}, nil
+	}
+
+	key, n := ParseKey(t.pending)
+	t.pending = t.pending[n:]
+
+	return key, nil
+}
+
+// Write writes raw output to the terminal.
+func (t *Terminal) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
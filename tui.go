@@ -0,0 +1,800 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/akamai-gtm/term"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+// tuiPane is one of the list panes of the tui command.
+type tuiPane int
+
+const (
+	paneDomains tuiPane = iota
+	paneProperties
+	paneDataCenters
+	paneCount
+)
+
+var tuiPaneTitles = [paneCount]string{"Domains", "Properties", "Data centers"}
+
+// tuiRow is a row of a list pane. Traffic targets and liveness tests are
+// children of the property at index prop.
+type tuiRow struct {
+	kind  string // domain, property, target, test or datacenter
+	label string
+	style term.Style
+	index int
+	prop  int
+}
+
+// tuiModal is a scrollable text shown over the panes, such as a diff
+// waiting for confirmation or the key help.
+type tuiModal struct {
+	title   string
+	lines   []string
+	offset  int
+	confirm func() error
+}
+
+// browser is the state of the tui command.
+type browser struct {
+	api    gtm.API
+	term   *term.Terminal
+	screen *term.Screen
+
+	domains     []edgegrid.DomainSummary
+	domain      *edgegrid.Domain
+	status      *edgegrid.DomainStatus
+	properties  []edgegrid.Property
+	dataCenters []edgegrid.DataCenter
+	expanded    map[string]bool
+
+	focus  tuiPane
+	cursor [paneCount]int
+	offset [paneCount]int
+	filter [paneCount]string
+
+	searching    bool
+	query        string
+	detailOffset int
+	modal        *tuiModal
+	message      string
+	failed       bool
+	quit         bool
+}
+
+var tuiHelp = []string{
+	"Tab, 1-3       switch between the domain, property and data center panes",
+	"j/k, ↑/↓       move; PgUp/PgDn, g/G page and jump",
+	"Enter          open a domain; expand or collapse a property",
+	"→/l, ←/h       expand a property; collapse it or go to its parent",
+	"/              search the focused pane; Esc clears the search",
+	"J/K            scroll the details pane",
+	"t              enable or disable the selected traffic target",
+	"e              edit the selected property in $EDITOR",
+	"r              reload",
+	"?              this help",
+	"q, Ctrl-C      quit",
+	"",
+	"Changes are shown as a diff and submitted only after pressing y.",
+}
+
+func tui(c *cli.Context) error {
+	t, err := term.Open()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	b := &browser{
+		api:      client(c),
+		term:     t,
+		screen:   term.NewScreen(0, 0),
+		expanded: map[string]bool{},
+	}
+	b.loadDomains()
+	if name := c.Args().First(); name != "" {
+		b.openDomain(name)
+		b.focus = paneProperties
+	}
+
+	return b.run()
+}
+
+func (b *browser) run() error {
+	dirty := true
+	for !b.quit {
+		width, height, err := b.term.Size()
+		if err != nil {
+			return err
+		}
+		if w, h := b.screen.Size(); w != width || h != height {
+			b.screen.Resize(width, height)
+			dirty = true
+		}
+		if dirty {
+			b.draw()
+			if err := b.screen.Flush(b.term); err != nil {
+				return err
+			}
+		}
+
+		key, err := b.term.ReadKey()
+		if err != nil {
+			return err
+		}
+		dirty = key.Code != term.KeyNone
+		if dirty {
+			b.handleKey(key)
+		}
+	}
+
+	return nil
+}
+
+// setMessage shows a message in the status line until the next key.
+func (b *browser) setMessage(err error, format string, args ...interface{}) {
+	if err != nil {
+		b.message, b.failed = err.Error(), true
+		return
+	}
+	b.message, b.failed = fmt.Sprintf(format, args...), false
+}
+
+// busy shows a message while a slow API call runs.
+func (b *browser) busy(format string, args ...interface{}) {
+	b.setMessage(nil, format, args...)
+	b.draw()
+	b.screen.Flush(b.term)
+}
+
+func (b *browser) loadDomains() {
+	b.busy("Loading domains...")
+	domains, err := b.api.Domains()
+	if err != nil {
+		b.setMessage(err, "")
+		return
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Name < domains[j].Name })
+	b.domains = domains
+	b.setMessage(nil, "%d domains", len(domains))
+}
+
+func (b *browser) openDomain(name string) {
+	b.busy("Loading %s...", name)
+	domain, err := b.api.Domain(name)
+	if err != nil {
+		b.setMessage(err, "")
+		return
+	}
+	status, err := b.api.DomainStatus(name)
+	if err != nil {
+		b.setMessage(err, "")
+		return
+	}
+	dcs, err := b.api.DataCenters(name)
+	if err != nil {
+		b.setMessage(err, "")
+		return
+	}
+	props, err := b.api.Properties(name)
+	if err != nil {
+		b.setMessage(err, "")
+		return
+	}
+	sort.Slice(dcs, func(i, j int) bool { return dcs[i].Nickname < dcs[j].Nickname })
+	sort.Slice(props.Properties, func(i, j int) bool { return props.Properties[i].Name < props.Properties[j].Name })
+
+	if b.domain == nil || b.domain.Name != name {
+		b.expanded = map[string]bool{}
+		b.cursor[paneProperties], b.offset[paneProperties] = 0, 0
+		b.cursor[paneDataCenters], b.offset[paneDataCenters] = 0, 0
+		b.filter[paneProperties], b.filter[paneDataCenters] = "", ""
+	}
+	b.domain, b.status, b.dataCenters, b.properties = domain, status, dcs, props.Properties
+	b.setMessage(nil, "%s: %d properties, %d data centers", name, len(b.properties), len(b.dataCenters))
+}
+
+func (b *browser) reload() {
+	b.loadDomains()
+	if b.domain != nil && !b.failed {
+		b.openDomain(b.domain.Name)
+	}
+}
+
+// rows returns the visible rows of a pane, after applying its search
+// filter.
+func (b *browser) rows(pane tuiPane) []tuiRow {
+	filter := strings.ToLower(b.filter[pane])
+	match := func(values ...string) bool {
+		if filter == "" {
+			return true
+		}
+		for _, v := range values {
+			if strings.Contains(strings.ToLower(v), filter) {
+				return true
+			}
+		}
+		return false
+	}
+
+	rows := []tuiRow{}
+	switch pane {
+	case paneDomains:
+		for i, d := range b.domains {
+			if match(d.Name) {
+				style := term.StyleNormal
+				if b.domain != nil && d.Name == b.domain.Name {
+					style = term.StyleBold
+				}
+				rows = append(rows, tuiRow{kind: "domain", label: d.Name, style: style, index: i})
+			}
+		}
+	case paneProperties:
+		for i, prop := range b.properties {
+			children := []tuiRow{}
+			childMatched := false
+			for j, target := range prop.TrafficTargets {
+				label := fmt.Sprintf("target %s", b.dataCenterName(target.DataCenterID))
+				style := term.StyleGreen
+				if !target.Enabled {
+					label += " (disabled)"
+					style = term.StyleDim
+				}
+				if filter != "" && match(append([]string{label}, target.Servers...)...) {
+					childMatched = true
+				}
+				children = append(children, tuiRow{kind: "target", label: label, style: style, index: j, prop: i})
+			}
+			for j, test := range prop.LivenessTests {
+				label := fmt.Sprintf("test %s (%s)", test.Name, test.TestObjectProtocol)
+				if filter != "" && match(label, test.TestObject) {
+					childMatched = true
+				}
+				children = append(children, tuiRow{kind: "test", label: label, index: j, prop: i})
+			}
+			if !match(prop.Name, prop.Type) && !childMatched {
+				continue
+			}
+
+			expanded := b.expanded[prop.Name] || childMatched
+			marker := "▸ "
+			if expanded {
+				marker = "▾ "
+			}
+			rows = append(rows, tuiRow{kind: "property", label: marker + prop.Name + "  " + prop.Type, index: i, prop: i})
+			if expanded {
+				for k, child := range children {
+					branch := "   ├ "
+					if k == len(children)-1 {
+						branch = "   └ "
+					}
+					child.label = branch + child.label
+					rows = append(rows, child)
+				}
+			}
+		}
+	case paneDataCenters:
+		for i, dc := range b.dataCenters {
+			id := strconv.Itoa(dc.DataCenterID)
+			if match(dc.Nickname, id, dc.City, dc.Country) {
+				rows = append(rows, tuiRow{kind: "datacenter", label: fmt.Sprintf("%s (%s)", dc.Nickname, id), index: i})
+			}
+		}
+	}
+
+	return rows
+}
+
+// selected returns the row under the cursor of the focused pane.
+func (b *browser) selected() (tuiRow, bool) {
+	rows := b.rows(b.focus)
+	i := b.cursor[b.focus]
+	if i < 0 || i >= len(rows) {
+		return tuiRow{}, false
+	}
+
+	return rows[i], true
+}
+
+func (b *browser) dataCenterName(id int) string {
+	for _, dc := range b.dataCenters {
+		if dc.DataCenterID == id {
+			return fmt.Sprintf("%s (%d)", dc.Nickname, id)
+		}
+	}
+
+	return strconv.Itoa(id)
+}
+
+func (b *browser) handleKey(key term.Key) {
+	b.message, b.failed = "", false
+
+	if b.modal != nil {
+		b.handleModalKey(key)
+		return
+	}
+	if b.searching {
+		b.handleSearchKey(key)
+		return
+	}
+
+	rows := b.rows(b.focus)
+	switch {
+	case key.Is('q') || key.IsCtrl('c'):
+		b.quit = true
+	case key.Code == term.KeyTab:
+		b.setFocus((b.focus + 1) % paneCount)
+	case key.Code == term.KeyBacktab:
+		b.setFocus((b.focus + paneCount - 1) % paneCount)
+	case key.Is('1'), key.Is('2'), key.Is('3'):
+		b.setFocus(tuiPane(key.Rune - '1'))
+	case key.Is('j') || key.Code == term.KeyDown:
+		b.move(1, len(rows))
+	case key.Is('k') || key.Code == term.KeyUp:
+		b.move(-1, len(rows))
+	case key.Code == term.KeyPgDn || key.IsCtrl('d'):
+		b.move(b.paneHeight(b.focus), len(rows))
+	case key.Code == term.KeyPgUp || key.IsCtrl('u'):
+		b.move(-b.paneHeight(b.focus), len(rows))
+	case key.Is('g') || key.Code == term.KeyHome:
+		b.move(-len(rows), len(rows))
+	case key.Is('G') || key.Code == term.KeyEnd:
+		b.move(len(rows), len(rows))
+	case key.Is('J'):
+		b.detailOffset++
+	case key.Is('K'):
+		if b.detailOffset > 0 {
+			b.detailOffset--
+		}
+	case key.Is('/'):
+		b.searching, b.query = true, b.filter[b.focus]
+	case key.Code == term.KeyEsc:
+		b.filter[b.focus] = ""
+		b.cursor[b.focus] = 0
+	case key.Code == term.KeyEnter:
+		b.activate(true)
+	case key.Is('l') || key.Code == term.KeyRight:
+		b.activate(false)
+	case key.Is('h') || key.Code == term.KeyLeft:
+		b.collapse()
+	case key.Is('t'):
+		b.toggleTarget()
+	case key.Is('e'):
+		b.editProperty()
+	case key.Is('r'):
+		b.reload()
+	case key.Is('?'):
+		b.modal = &tuiModal{title: "Keys", lines: tuiHelp}
+	}
+}
+
+func (b *browser) handleModalKey(key term.Key) {
+	m := b.modal
+	switch {
+	case key.Is('j') || key.Code == term.KeyDown:
+		m.offset++
+	case key.Is('k') || key.Code == term.KeyUp:
+		m.offset--
+	case key.Code == term.KeyPgDn || key.Is(' '):
+		m.offset += b.paneHeight(paneProperties)
+	case key.Code == term.KeyPgUp:
+		m.offset -= b.paneHeight(paneProperties)
+	case key.Is('y') && m.confirm != nil:
+		b.modal = nil
+		if err := m.confirm(); err != nil {
+			b.setMessage(err, "")
+		}
+	case key.Is('n') || key.Is('q') || key.Code == term.KeyEsc || key.IsCtrl('c') ||
+		(m.confirm == nil && key.Code == term.KeyEnter):
+		b.modal = nil
+		if m.confirm != nil {
+			b.setMessage(nil, "Cancelled")
+		}
+	}
+	if m.offset > len(m.lines)-1 {
+		m.offset = len(m.lines) - 1
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
+
+func (b *browser) handleSearchKey(key term.Key) {
+	switch key.Code {
+	case term.KeyRune:
+		b.query += string(key.Rune)
+	case term.KeyBackspace:
+		if r := []rune(b.query); len(r) > 0 {
+			b.query = string(r[:len(r)-1])
+		}
+	case term.KeyEnter:
+		b.searching = false
+	case term.KeyEsc:
+		b.searching, b.query = false, ""
+	case term.KeyCtrl:
+		if key.Rune == 'c' {
+			b.searching, b.query = false, ""
+		}
+	}
+	b.filter[b.focus] = b.query
+	b.cursor[b.focus], b.offset[b.focus] = 0, 0
+}
+
+func (b *browser) setFocus(pane tuiPane) {
+	b.focus = pane
+	b.detailOffset = 0
+}
+
+func (b *browser) move(delta, count int) {
+	i := b.cursor[b.focus] + delta
+	if i >= count {
+		i = count - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	b.cursor[b.focus] = i
+	b.detailOffset = 0
+}
+
+// activate opens the selected domain, or expands (or with toggle, toggles)
+// the selected property.
+func (b *browser) activate(toggle bool) {
+	row, ok := b.selected()
+	if !ok {
+		return
+	}
+	switch row.kind {
+	case "domain":
+		b.openDomain(b.domains[row.index].Name)
+		if !b.failed {
+			b.setFocus(paneProperties)
+		}
+	case "property":
+		name := b.properties[row.prop].Name
+		b.expanded[name] = !(toggle && b.expanded[name])
+	}
+}
+
+func (b *browser) collapse() {
+	row, ok := b.selected()
+	if !ok || b.focus != paneProperties {
+		return
+	}
+	b.expanded[b.properties[row.prop].Name] = false
+	for i, r := range b.rows(paneProperties) {
+		if r.kind == "property" && r.prop == row.prop {
+			b.cursor[paneProperties] = i
+			break
+		}
+	}
+}
+
+// toggleTarget flips Enabled on the selected traffic target and asks for
+// confirmation of the resulting property change.
+func (b *browser) toggleTarget() {
+	row, ok := b.selected()
+	if !ok || row.kind != "target" {
+		b.setMessage(fmt.Errorf("select a traffic target to enable or disable it"), "")
+		return
+	}
+	live := b.properties[row.prop]
+	updated := &edgegrid.Property{}
+	if err := fromDocument(live, updated); err != nil {
+		b.setMessage(err, "")
+		return
+	}
+	target := &updated.TrafficTargets[row.index]
+	target.Enabled = !target.Enabled
+
+	action := "Enable"
+	if !target.Enabled {
+		action = "Disable"
+	}
+	b.confirmUpdate(fmt.Sprintf("%s %s in %s", action, b.dataCenterName(target.DataCenterID), live.Name), &live, updated)
+}
+
+// editProperty opens the selected property in $EDITOR and asks for
+// confirmation of the changes made.
+func (b *browser) editProperty() {
+	row, ok := b.selected()
+	if !ok || b.focus != paneProperties {
+		b.setMessage(fmt.Errorf("select a property to edit it"), "")
+		return
+	}
+	live := b.properties[row.prop]
+
+	if err := b.term.Suspend(); err != nil {
+		b.setMessage(err, "")
+		return
+	}
+	edited := &edgegrid.Property{}
+	_, err := editObject(live.Name, "yaml", live, edited)
+	if rerr := b.term.Resume(); rerr != nil && err == nil {
+		err = rerr
+	}
+	b.screen.Resize(b.screen.Size())
+	if err != nil {
+		b.setMessage(err, "")
+		return
+	}
+	if edited.Name != live.Name {
+		b.setMessage(fmt.Errorf("the property name cannot be changed from %s to %s", live.Name, edited.Name), "")
+		return
+	}
+
+	b.confirmUpdate("Update "+live.Name, &live, edited)
+}
+
+// confirmUpdate shows the diff between a live property and its update and
+// submits the update once confirmed.
+func (b *browser) confirmUpdate(title string, live, updated *edgegrid.Property) {
+	diff, err := jsonDiff("live", "updated", live, updated)
+	if err != nil {
+		b.setMessage(err, "")
+		return
+	}
+	if diff == "" {
+		b.setMessage(nil, "No changes")
+		return
+	}
+
+	domain := b.domain.Name
+	b.modal = &tuiModal{
+		title: title + "? (y/n)",
+		lines: strings.Split(strings.TrimRight(diff, "\n"), "\n"),
+		confirm: func() error {
+			b.busy("Updating %s...", updated.Name)
+			resp, err := b.api.PropertyUpdate(domain, updated)
+			if err != nil {
+				return err
+			}
+			b.openDomain(domain)
+			if resp.Status != nil {
+				b.setMessage(nil, "Updated %s: %s", updated.Name, resp.Status.PropagationStatus)
+			} else {
+				b.setMessage(nil, "Updated %s", updated.Name)
+			}
+			return nil
+		},
+	}
+}
+
+// layout returns the position and size of each list pane and of the
+// details pane.
+func (b *browser) layout() (panes [paneCount][4]int, details [4]int) {
+	width, height := b.screen.Size()
+	body := height - 2
+	left := width * 2 / 5
+	if left < 30 {
+		left = 30
+	}
+
+	domainsHeight := body / 4
+	if domainsHeight < 4 {
+		domainsHeight = 4
+	}
+	dcsHeight := body / 4
+	if dcsHeight < 4 {
+		dcsHeight = 4
+	}
+	propsHeight := body - domainsHeight - dcsHeight
+
+	panes[paneDomains] = [4]int{0, 1, left, domainsHeight}
+	panes[paneProperties] = [4]int{0, 1 + domainsHeight, left, propsHeight}
+	panes[paneDataCenters] = [4]int{0, 1 + domainsHeight + propsHeight, left, dcsHeight}
+	details = [4]int{left, 1, width - left, body}
+
+	return panes, details
+}
+
+// paneHeight returns the number of rows visible in a pane.
+func (b *browser) paneHeight(pane tuiPane) int {
+	panes, _ := b.layout()
+	if h := panes[pane][3] - 2; h > 0 {
+		return h
+	}
+
+	return 1
+}
+
+func (b *browser) draw() {
+	s := b.screen
+	s.Clear()
+	width, height := s.Size()
+	if width < 60 || height < 16 {
+		s.Text(0, 0, width, "The terminal is too small", term.StyleNormal)
+		return
+	}
+
+	title := " akamai-gtm"
+	if b.domain != nil {
+		title += "  " + b.domain.Name
+		if b.status != nil {
+			title += fmt.Sprintf("  %s  change %s", b.status.PropagationStatus, b.status.ChangeID)
+		}
+	}
+	s.Line(0, 0, width, title, term.StyleTitle)
+
+	panes, details := b.layout()
+	for pane := tuiPane(0); pane < paneCount; pane++ {
+		b.drawPane(pane, panes[pane])
+	}
+	b.drawDetails(details)
+	if b.modal != nil {
+		b.drawModal()
+	}
+	b.drawStatusLine()
+}
+
+func (b *browser) drawPane(pane tuiPane, rect [4]int) {
+	s := b.screen
+	x, y, w, h := rect[0], rect[1], rect[2], rect[3]
+	rows := b.rows(pane)
+
+	title := fmt.Sprintf("%d %s", pane+1, tuiPaneTitles[pane])
+	if b.filter[pane] != "" {
+		title += fmt.Sprintf(" /%s (%d)", b.filter[pane], len(rows))
+	}
+	border := term.StyleDim
+	if pane == b.focus {
+		border = term.StyleCyan
+	}
+	s.Box(x, y, w, h, title, border)
+
+	visible := h - 2
+	if b.cursor[pane] >= len(rows) {
+		b.cursor[pane] = len(rows) - 1
+	}
+	if b.cursor[pane] < 0 {
+		b.cursor[pane] = 0
+	}
+	if b.cursor[pane] < b.offset[pane] {
+		b.offset[pane] = b.cursor[pane]
+	}
+	if b.cursor[pane] >= b.offset[pane]+visible {
+		b.offset[pane] = b.cursor[pane] - visible + 1
+	}
+
+	for i := 0; i < visible && b.offset[pane]+i < len(rows); i++ {
+		row := rows[b.offset[pane]+i]
+		style := row.style
+		if pane == b.focus && b.offset[pane]+i == b.cursor[pane] {
+			style = term.StyleReverse
+		}
+		s.Line(x+1, y+1+i, w-2, " "+row.label, style)
+	}
+	if len(rows) == 0 {
+		empty := "no matches"
+		if b.filter[pane] == "" {
+			empty = "none"
+			if pane != paneDomains && b.domain == nil {
+				empty = "open a domain with Enter"
+			}
+		}
+		s.Text(x+2, y+1, w-4, empty, term.StyleDim)
+	}
+}
+
+// detailLines renders the object selected in the focused pane.
+func (b *browser) detailLines() (string, []string) {
+	row, ok := b.selected()
+	if !ok {
+		return "Details", nil
+	}
+
+	buf := &bytes.Buffer{}
+	title := "Details"
+	switch row.kind {
+	case "domain":
+		summary := b.domains[row.index]
+		title = summary.Name
+		if b.domain != nil && b.domain.Name == summary.Name && b.domain.Status != nil {
+			render.Domain(buf, b.domain)
+		} else {
+			render.BasicTable(buf, [][]string{
+				{"Name", summary.Name},
+				{"Status", summary.Status},
+				{"Last Modified", summary.LastModified},
+				{"", "press Enter to open"},
+			})
+		}
+	case "property":
+		prop := b.properties[row.prop]
+		title = prop.Name
+		render.Property(buf, &prop)
+	case "target":
+		prop := b.properties[row.prop]
+		target := prop.TrafficTargets[row.index]
+		title = prop.Name + " → " + b.dataCenterName(target.DataCenterID)
+		render.TrafficTarget(buf, target)
+	case "test":
+		prop := b.properties[row.prop]
+		test := prop.LivenessTests[row.index]
+		title = prop.Name + " → " + test.Name
+		render.LivenessTest(buf, test)
+	case "datacenter":
+		dc := b.dataCenters[row.index]
+		title = dc.Nickname
+		render.DataCenter(buf, &dc)
+	}
+
+	return title, strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
+
+func (b *browser) drawDetails(rect [4]int) {
+	s := b.screen
+	x, y, w, h := rect[0], rect[1], rect[2], rect[3]
+	title, lines := b.detailLines()
+	s.Box(x, y, w, h, title, term.StyleDim)
+
+	if b.detailOffset > len(lines)-1 {
+		b.detailOffset = len(lines) - 1
+	}
+	if b.detailOffset < 0 {
+		b.detailOffset = 0
+	}
+	for i := 0; i < h-2 && b.detailOffset+i < len(lines); i++ {
+		s.Text(x+2, y+1+i, w-4, lines[b.detailOffset+i], term.StyleNormal)
+	}
+}
+
+func (b *browser) drawModal() {
+	s := b.screen
+	width, height := s.Size()
+	x, y, w, h := 2, 2, width-4, height-4
+	m := b.modal
+
+	s.Fill(x, y, w, h, term.StyleNormal)
+	s.Box(x, y, w, h, m.title, term.StyleYellow)
+	for i := 0; i < h-2 && m.offset+i < len(m.lines); i++ {
+		line := m.lines[m.offset+i]
+		style := term.StyleNormal
+		if m.confirm != nil {
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+				style = term.StyleBold
+			case strings.HasPrefix(line, "+"):
+				style = term.StyleGreen
+			case strings.HasPrefix(line, "-"):
+				style = term.StyleRed
+			case strings.HasPrefix(line, "@@"):
+				style = term.StyleCyan
+			}
+		}
+		s.Text(x+2, y+1+i, w-4, line, style)
+	}
+}
+
+func (b *browser) drawStatusLine() {
+	s := b.screen
+	width, height := s.Size()
+	y := height - 1
+
+	switch {
+	case b.searching:
+		s.Line(0, y, width, "/"+b.query+"▏", term.StyleNormal)
+	case b.message != "":
+		style := term.StyleNormal
+		if b.failed {
+			style = term.StyleRed
+		}
+		s.Line(0, y, width, " "+b.message, style)
+	case b.modal != nil && b.modal.confirm != nil:
+		s.Line(0, y, width, " y submit · n cancel · j/k scroll", term.StyleDim)
+	case b.modal != nil:
+		s.Line(0, y, width, " Esc close · j/k scroll", term.StyleDim)
+	default:
+		s.Line(0, y, width, " Tab pane · / search · Enter open · t toggle target · e edit · r reload · ? help · q quit", term.StyleDim)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/comcast/akamai-gtm/gtm"
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/urfave/cli"
+)
+
+func dataCenterUsage(c *cli.Context) error {
+	domain := c.Args().First()
+	id := c.Int("id")
+	refs, err := gtm.DataCenterReferences(client(c), domain)
+	if err != nil {
+		return err
+	}
+
+	if len(refs[id]) == 0 {
+		fmt.Printf("No references found for data center %d\n", id)
+		return nil
+	}
+
+	render.References(os.Stdout, refs[id])
+
+	return nil
+}
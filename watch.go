@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comcast/akamai-gtm/term"
+	"github.com/urfave/cli"
+)
+
+// defaultWatchInterval is the refresh interval of a bare --watch.
+const defaultWatchInterval = 5 * time.Second
+
+// watchInterval is the value of --watch. It acts as a boolean flag, so
+// --watch may be given without an interval; an interval may follow as
+// --watch=10s or as the next argument (see watchArgs).
+type watchInterval struct {
+	interval time.Duration
+}
+
+func (w *watchInterval) IsBoolFlag() bool {
+	return true
+}
+
+func (w *watchInterval) String() string {
+	if w == nil || w.interval == 0 {
+		return ""
+	}
+
+	return w.interval.String()
+}
+
+func (w *watchInterval) Set(value string) error {
+	switch value {
+	case "true":
+		w.interval = defaultWatchInterval
+		return nil
+	case "false":
+		w.interval = 0
+		return nil
+	}
+	interval, ok := parseWatchInterval(value)
+	if !ok {
+		return fmt.Errorf("invalid interval %q: expected a duration such as 10s, or seconds", value)
+	}
+	w.interval = interval
+
+	return nil
+}
+
+// parseWatchInterval parses a duration such as 10s, or a number of
+// seconds as watch(1) takes.
+func parseWatchInterval(value string) (time.Duration, bool) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, true
+	}
+
+	return 0, false
+}
+
+// watchFlag returns the --watch flag. Each command needs its own, as the
+// flag holds its value.
+func watchFlag() cli.Flag {
+	return cli.GenericFlag{
+		Name:  "watch",
+		Value: &watchInterval{},
+		Usage: fmt.Sprintf("Refresh every interval (default %s) until interrupted, highlighting changes; prints changes as JSON lines when stdout is not a terminal", defaultWatchInterval),
+	}
+}
+
+// watchArgs returns the --watch interval, or 0 without --watch, and the
+// positional arguments. An interval given as the argument after --watch
+// ends up first among the positional arguments and is removed from them.
+func watchArgs(c *cli.Context) (time.Duration, cli.Args) {
+	args := c.Args()
+	w, ok := c.Generic("watch").(*watchInterval)
+	if !ok || w.interval == 0 {
+		return 0, args
+	}
+	if len(args) > 1 {
+		if interval, ok := parseWatchInterval(args[0]); ok {
+			return interval, args[1:]
+		}
+	}
+
+	return w.interval, args
+}
+
+// watchFields records the fields of a command's output that are compared
+// between refreshes, keyed by a path such as www/3131/Enabled.
+type watchFields map[string]string
+
+// addRows records name, value rows, prefixing the names with prefix.
+func (f watchFields) addRows(prefix string, rows [][]string) {
+	for _, row := range rows {
+		if len(row) == 2 {
+			f[prefix+row[0]] = row[1]
+		}
+	}
+}
+
+// watchOutput writes a command's output to w and records its fields.
+type watchOutput func(w io.Writer, args cli.Args, fields watchFields) error
+
+// watchEvent is a change reported by --watch when stdout is not a
+// terminal.
+type watchEvent struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Domain  string    `json:"domain"`
+	Event   string    `json:"event"`
+	Field   string    `json:"field,omitempty"`
+	Old     *string   `json:"old,omitempty"`
+	New     *string   `json:"new,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// watched runs a command's output once, or with --watch until interrupted:
+// redrawn in place on a terminal, or as JSON change events otherwise.
+func watched(c *cli.Context, output watchOutput) error {
+	interval, args := watchArgs(c)
+	if interval == 0 {
+		return output(os.Stdout, args, watchFields{})
+	}
+
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		return watchTerminal(c, interval, args, output)
+	}
+
+	return watchEvents(c, interval, args, output)
+}
+
+// watchTerminal redraws the output every interval, highlighting the lines
+// that changed since the previous refresh.
+func watchTerminal(c *cli.Context, interval time.Duration, args cli.Args, output watchOutput) error {
+	header := fmt.Sprintf("Every %s: %s %s %s", interval, c.App.Name, c.Command.Name, strings.Join(args, " "))
+	previous := map[string]bool(nil)
+
+	fmt.Print("\x1b[H\x1b[2J")
+	for {
+		buf := &bytes.Buffer{}
+		err := output(buf, args, watchFields{})
+		if err != nil && previous == nil {
+			return err
+		}
+
+		frame := &bytes.Buffer{}
+		fmt.Fprintf(frame, "\x1b[H\x1b[1m%s\x1b[0m\x1b[K\n", alignRight(header, time.Now().Format("15:04:05")))
+		if err != nil {
+			fmt.Fprintf(frame, "\x1b[31m%s\x1b[0m\x1b[K\n", err)
+		} else {
+			fmt.Fprint(frame, "\x1b[K\n")
+			current := map[string]bool{}
+			for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+				current[line] = true
+				if previous != nil && !previous[line] {
+					fmt.Fprintf(frame, "\x1b[1;33m%s\x1b[0m\x1b[K\n", line)
+				} else {
+					fmt.Fprintf(frame, "%s\x1b[K\n", line)
+				}
+			}
+			previous = current
+		}
+		fmt.Fprint(frame, "\x1b[J")
+		os.Stdout.Write(frame.Bytes())
+
+		time.Sleep(interval)
+	}
+}
+
+// alignRight joins left and right with enough space to align right with
+// the edge of the terminal.
+func alignRight(left, right string) string {
+	width, _, err := term.Size(int(os.Stdout.Fd()))
+	if err != nil || len(left)+len(right)+1 > width {
+		return left + "  " + right
+	}
+
+	return left + strings.Repeat(" ", width-len(left)-len(right)) + right
+}
+
+// watchEvents writes a JSON line for each field that changed, appeared or
+// disappeared since the previous refresh, and for each failed refresh. The
+// first refresh is the baseline and is not reported.
+func watchEvents(c *cli.Context, interval time.Duration, args cli.Args, output watchOutput) error {
+	enc := json.NewEncoder(os.Stdout)
+	event := func(kind string) watchEvent {
+		return watchEvent{Time: time.Now().UTC(), Command: c.Command.Name, Domain: args.First(), Event: kind}
+	}
+
+	previous := watchFields(nil)
+	for {
+		fields := watchFields{}
+		err := output(ioutil.Discard, args, fields)
+		switch {
+		case err != nil && previous == nil:
+			return err
+		case err != nil:
+			e := event("error")
+			e.Error = err.Error()
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		default:
+			if previous != nil {
+				for _, e := range fieldChanges(previous, fields, event) {
+					if err := enc.Encode(e); err != nil {
+						return err
+					}
+				}
+			}
+			previous = fields
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// fieldChanges returns an event for each field that differs between
+// previous and current, ordered by field.
+func fieldChanges(previous, current watchFields, event func(kind string) watchEvent) []watchEvent {
+	names := []string{}
+	for name := range previous {
+		names = append(names, name)
+	}
+	for name := range current {
+		if _, ok := previous[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	events := []watchEvent{}
+	for _, name := range names {
+		old, hadOld := previous[name]
+		value, hasNew := current[name]
+		var e watchEvent
+		switch {
+		case !hadOld:
+			e = event("added")
+			e.New = &value
+		case !hasNew:
+			e = event("removed")
+			e.Old = &old
+		case old != value:
+			e = event("changed")
+			e.Old, e.New = &old, &value
+		default:
+			continue
+		}
+		e.Field = name
+		events = append(events, e)
+	}
+
+	return events
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comcast/akamai-gtm/render"
+	"github.com/comcast/go-edgegrid/edgegrid"
+	"github.com/urfave/cli"
+)
+
+var weightsFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "name",
+		Usage: "The Property name, to report on the live Property",
+	},
+	cli.StringFlag{
+		Name:  "file, json",
+		Usage: "The path to a YAML or JSON Property file, or - for stdin, to report on instead of the live Property",
+	},
+	cli.IntFlag{
+		Name:  "samples",
+		Usage: "The number of DNS queries to sample through the simulate model, which checks that model against itself rather than live traffic; 0 to skip",
+	},
+	cli.Int64Flag{
+		Name:  "seed",
+		Usage: "The random seed for sampling; defaults to the current time",
+	},
+}
+
+func weights(c *cli.Context) error {
+	prop, err := simulatedProperty(c)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(prop.Type, "weighted-") {
+		return fmt.Errorf("property %s is of type %s; weights only determine the traffic split of weighted properties", prop.Name, prop.Type)
+	}
+	names, err := dataCenterNames(c)
+	if err != nil {
+		return err
+	}
+
+	state := simState{}
+	live := liveTargets(prop, state)
+	if len(live) == 0 {
+		return fmt.Errorf("property %s has no enabled traffic targets with servers", prop.Name)
+	}
+	shares := weightShares(live)
+
+	fmt.Printf("Traffic split for %s (%s)\n", prop.Name, prop.Type)
+	data := [][]string{}
+	for _, target := range prop.TrafficTargets {
+		data = append(data, []string{
+			dcLabel(target.DataCenterID, names),
+			strconv.FormatBool(target.Enabled),
+			strconv.FormatFloat(target.Weight, 'f', -1, 64),
+			percent(shares[target.DataCenterID]),
+		})
+	}
+	render.TableWithHeaders(os.Stdout, []string{"DataCenter", "Enabled", "Weight", "Share"}, data)
+
+	fmt.Printf("\nPer server\n")
+	data = [][]string{}
+	for _, target := range live {
+		for _, server := range serverShares(target, shares[target.DataCenterID]) {
+			data = append(data, []string{dcLabel(target.DataCenterID, names), server.name, percent(server.share)})
+		}
+	}
+	render.TableWithHeaders(os.Stdout, []string{"DataCenter", "Server", "Share"}, data)
+
+	if len(live) > 1 {
+		fmt.Printf("\nSplit when a data center is down\n")
+		headers := []string{"Down"}
+		for _, target := range live {
+			headers = append(headers, dcLabel(target.DataCenterID, names))
+		}
+		data = [][]string{}
+		for _, down := range live {
+			remaining := []edgegrid.TrafficTarget{}
+			for _, target := range live {
+				if target.DataCenterID != down.DataCenterID {
+					remaining = append(remaining, target)
+				}
+			}
+			redistributed := weightShares(remaining)
+			row := []string{dcLabel(down.DataCenterID, names)}
+			for _, target := range live {
+				if target.DataCenterID == down.DataCenterID {
+					row = append(row, "-")
+					continue
+				}
+				row = append(row, fmt.Sprintf("%s (%+.2f)", percent(redistributed[target.DataCenterID]), (redistributed[target.DataCenterID]-shares[target.DataCenterID])*100))
+			}
+			data = append(data, row)
+		}
+		render.TableWithHeaders(os.Stdout, headers, data)
+	}
+
+	if samples := c.Int("samples"); samples > 0 {
+		seed := c.Int64("seed")
+		if !c.IsSet("seed") {
+			seed = time.Now().UnixNano()
+		}
+		fmt.Printf("\nSampled %d queries (seed %d)\n", samples, seed)
+		observed, err := sampleShares(prop, samples, rand.New(rand.NewSource(seed)))
+		if err != nil {
+			return err
+		}
+		data = [][]string{}
+		maxDeviation := 0.0
+		for _, target := range live {
+			id := target.DataCenterID
+			deviation := (observed[id] - shares[id]) * 100
+			maxDeviation = math.Max(maxDeviation, math.Abs(deviation))
+			data = append(data, []string{dcLabel(id, names), percent(shares[id]), percent(observed[id]), fmt.Sprintf("%+.2f", deviation)})
+		}
+		render.TableWithHeaders(os.Stdout, []string{"DataCenter", "Expected", "Observed", "Deviation"}, data)
+		fmt.Printf("Maximum deviation: %.2f percentage points\n", maxDeviation)
+	}
+
+	return nil
+}
+
+// serverShare is the share of traffic received by one server.
+type serverShare struct {
+	name  string
+	share float64
+}
+
+// serverShares divides a target's share between its servers. Whether
+// addresses are rotated (normal, one-ip, all-live-ips) or hashed per client
+// (persistent, one-ip-hashed), each server receives an equal part, assuming
+// clients use the first address handed out. A handout CNAME receives the
+// whole share.
+func serverShares(target edgegrid.TrafficTarget, share float64) []serverShare {
+	if cname := render.InterfaceToStr(target.HandoutCname); cname != "" {
+		return []serverShare{{cname + " (CNAME)", share}}
+	}
+
+	servers := []serverShare{}
+	for _, server := range target.Servers {
+		servers = append(servers, serverShare{server, share / float64(len(target.Servers))})
+	}
+
+	return servers
+}
+
+// sampleShares simulates queries through simulateProperty, from a random
+// client address each for weighted-hashed properties, picks one of the
+// answers handed out by its probability and returns the observed share of
+// each data center. This checks the simulator against the shares computed
+// from the weights, i.e. the model against itself, not live traffic.
+func sampleShares(prop *edgegrid.Property, samples int, rnd *rand.Rand) (map[int]float64, error) {
+	counts := map[int]int{}
+	result, err := simulateProperty(prop, simState{})
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < samples; i++ {
+		if prop.Type == "weighted-hashed" {
+			client := fmt.Sprintf("%d.%d.%d.%d", rnd.Intn(256), rnd.Intn(256), rnd.Intn(256), rnd.Intn(256))
+			if result, err = simulateProperty(prop, simState{Client: client}); err != nil {
+				return nil, err
+			}
+		}
+		if len(result.Answers) == 0 {
+			return nil, fmt.Errorf("no answers to sample: no traffic target is handed out")
+		}
+		point := rnd.Float64()
+		cumulative := 0.0
+		answer := result.Answers[len(result.Answers)-1]
+		for _, a := range result.Answers {
+			cumulative += a.Probability
+			if point < cumulative {
+				answer = a
+				break
+			}
+		}
+		counts[answer.DataCenter]++
+	}
+
+	observed := map[int]float64{}
+	for id, count := range counts {
+		observed[id] = float64(count) / float64(samples)
+	}
+
+	return observed, nil
+}
+
+func percent(share float64) string {
+	return fmt.Sprintf("%.2f%%", share*100)
+}